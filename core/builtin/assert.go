@@ -1,7 +1,15 @@
 package builtin
 
 import (
+	"fmt"
+	"io"
+	"math"
+	"strings"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/expr"
 	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/shared"
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
 )
@@ -15,13 +23,145 @@ func builtinAssert(tok *token.Token, args ...types.Node) any {
 		serror.Add(args[1].GetToken(), "Argument error", "Too many arguments, expected 1 argument for assert builtin")
 		serror.Panic()
 	}
+	// comparisons of two values report both values instead of false
+	if eq, ok := args[0].(*expr.Equal); ok && len(eq.Children) == 2 {
+		actual, expected := eq.Children[0].Eval(), eq.Children[1].Eval()
+		if !expr.Equals(actual, expected, eq.Token) {
+			serror.AddNode(eq, "Assertion error", "Assertion failed, expected %s, got %s", shared.Repr(expected), shared.Repr(actual)).
+				Label(eq.Children[0].GetSpan(), "evaluates to %s", shared.Repr(actual)).
+				Label(eq.Children[1].GetSpan(), "evaluates to %s", shared.Repr(expected))
+			serror.Panic()
+		}
+		return nil
+	}
 	execution := args[0].Eval()
 	if res, ok := execution.(bool); !ok {
 		serror.Add(args[0].GetToken(), "Type error", "Expected assertion to be of type boolean, got %T", execution)
 		serror.Panic()
 	} else if !res {
-		serror.Add(args[0].GetToken(), "Assertion error", "Assertion failed, wanted true, got false")
+		serror.AddNode(args[0], "Assertion error", "Assertion failed, wanted true, got false")
+		serror.Panic()
+	}
+	return nil
+}
+
+// formats the source code of n followed by its value, such as
+// (square 4) = 16, only the value is returned for literals
+func operand(n types.Node, v any) string {
+	src, repr := serror.Text(n.GetSpan()), shared.Repr(v)
+	if src == "" || src == repr {
+		return repr
+	}
+	return src + " = " + repr
+}
+
+func builtinAssertEq(tok *token.Token, args ...types.Node) any {
+	if len(args) != 2 {
+		serror.Add(tok, "Argument error", "Expected 2 arguments for assert-eq builtin, the actual and the expected value")
+		serror.Panic()
+	}
+	actual, expected := args[0].Eval(), args[1].Eval()
+	differences := diff(actual, expected, tok)
+	if len(differences) == 0 {
+		return nil
+	}
+	info := strings.Builder{}
+	fmt.Fprintf(&info, "Assertion failed, values are not equal\n\tactual:   %s\n\texpected: %s", operand(args[0], actual), operand(args[1], expected))
+	if isContainer(actual) && isContainer(expected) {
+		info.WriteString("\n\tdiff:")
+		for _, d := range differences {
+			info.WriteString("\n\t\t")
+			info.WriteString(d)
+		}
+	}
+	serror.Add(tok, "Assertion error", "%s", info.String()).
+		Label(args[0].GetSpan(), "evaluates to %s", shared.Repr(actual)).
+		Label(args[1].GetSpan(), "evaluates to %s", shared.Repr(expected))
+	serror.Panic()
+	return nil
+}
+
+func builtinAssertNe(tok *token.Token, args ...types.Node) any {
+	if len(args) != 2 {
+		serror.Add(tok, "Argument error", "Expected 2 arguments for assert-ne builtin")
+		serror.Panic()
+	}
+	a, b := args[0].Eval(), args[1].Eval()
+	if len(diff(a, b, tok)) != 0 {
+		return nil
+	}
+	serror.Add(tok, "Assertion error", "Assertion failed, values are equal\n\tleft:  %s\n\tright: %s", operand(args[0], a), operand(args[1], b)).
+		Label(args[0].GetSpan(), "evaluates to %s", shared.Repr(a)).
+		Label(args[1].GetSpan(), "evaluates to %s", shared.Repr(b))
+	serror.Panic()
+	return nil
+}
+
+// tolerance of assert-approx if called without epsilon
+const APPROX_EPSILON = 1e-9
+
+func builtinAssertApprox(tok *token.Token, args ...types.Node) any {
+	if len(args) != 2 && len(args) != 3 {
+		serror.Add(tok, "Argument error", "Expected 2 or 3 arguments for assert-approx builtin, the actual value, the expected value and optionally the tolerance")
 		serror.Panic()
 	}
+	values := make([]float64, len(args))
+	for i, arg := range args {
+		switch v := arg.Eval().(type) {
+		case float64:
+			values[i] = v
+		case types.Decimal:
+			values[i], _ = v.Float64()
+		default:
+			serror.Add(arg.GetToken(), "Type error", "Expected value of type float or decimal, got %s", types.Of(v))
+			serror.Panic()
+		}
+	}
+	epsilon := APPROX_EPSILON
+	if len(values) == 3 {
+		epsilon = values[2]
+	}
+	actual, expected := values[0], values[1]
+	if math.Abs(actual-expected) <= epsilon {
+		return nil
+	}
+	serror.Add(tok, "Assertion error", "Assertion failed, values differ by %s, more than %s\n\tactual:   %s\n\texpected: %s",
+		shared.Repr(math.Abs(actual-expected)), shared.Repr(epsilon), operand(args[0], actual), operand(args[1], expected)).
+		Label(args[0].GetSpan(), "evaluates to %s", shared.Repr(actual)).
+		Label(args[1].GetSpan(), "evaluates to %s", shared.Repr(expected))
+	serror.Panic()
 	return nil
 }
+
+func builtinAssertThrows(tok *token.Token, args ...types.Node) any {
+	if len(args) < 1 {
+		serror.Add(tok, "Argument error", "Expected at least 1 argument for assert-throws builtin")
+		serror.Panic()
+	}
+	if v, thrown := throws(tok, args); !thrown {
+		last := args[len(args)-1]
+		serror.Add(tok, "Assertion error", "Assertion failed, expected a runtime error\n\tgot: %s", operand(last, v)).
+			Label(last.GetSpan(), "evaluates to %s", shared.Repr(v))
+		serror.Panic()
+	}
+	return nil
+}
+
+// evaluates body, reports whether a runtime error stopped the evaluation.
+// The error is discarded, other panics are not recovered.
+func throws(tok *token.Token, body []types.Node) (v any, thrown bool) {
+	defer serror.SetDefault(serror.Default())
+	serror.SetDefault(serror.NewFormatter(&core.CONF, "", tok.File, io.Discard))
+	defer func() {
+		if e := recover(); e != nil {
+			if _, ok := e.(*serror.Error); !ok {
+				panic(e)
+			}
+			thrown = true
+		}
+	}()
+	for _, n := range body {
+		v = n.Eval()
+	}
+	return v, false
+}
@@ -0,0 +1,51 @@
+package builtin
+
+import (
+	"io"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/alloc"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+func TestAssertions(t *testing.T) {
+	serror.SetDefault(serror.NewFormatter(&core.CONF, "", "test", io.Discard))
+	float := func(f float64) types.Node {
+		return &expr.Float{Token: &token.Token{}, Value: f}
+	}
+	fails := &expr.Call{Token: &token.Token{Raw: "assert"}, Key: alloc.Default.Functions["assert"], Args: []types.Node{&expr.Boolean{Token: &token.Token{}, Value: false}}}
+	tests := []struct {
+		name    string
+		builtin types.KnownFunctionInterface
+		args    []types.Node
+		fails   bool
+	}{
+		{name: "eq", builtin: builtinAssertEq, args: []types.Node{float(1), float(1)}},
+		{name: "eq fails", builtin: builtinAssertEq, args: []types.Node{float(1), float(2)}, fails: true},
+		{name: "ne", builtin: builtinAssertNe, args: []types.Node{float(1), float(2)}},
+		{name: "ne fails", builtin: builtinAssertNe, args: []types.Node{float(1), float(1)}, fails: true},
+		{name: "approx", builtin: builtinAssertApprox, args: []types.Node{float(0.1 + 0.2), float(0.3)}},
+		{name: "approx epsilon", builtin: builtinAssertApprox, args: []types.Node{float(1), float(1.5), float(0.5)}},
+		{name: "approx fails", builtin: builtinAssertApprox, args: []types.Node{float(1), float(1.1)}, fails: true},
+		{name: "throws", builtin: builtinAssertThrows, args: []types.Node{fails}},
+		{name: "throws fails", builtin: builtinAssertThrows, args: []types.Node{float(1)}, fails: true},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			failed := func() (failed bool) {
+				defer func() {
+					_, failed = recover().(*serror.Error)
+				}()
+				test.builtin(&token.Token{Raw: test.name}, test.args...)
+				return false
+			}()
+			if failed != test.fails {
+				t.Errorf("wanted failure %t, got %t", test.fails, failed)
+			}
+		})
+	}
+}
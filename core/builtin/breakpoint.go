@@ -0,0 +1,19 @@
+package builtin
+
+import (
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+func builtinBreakpoint(tok *token.Token, args ...types.Node) any {
+	if len(args) != 0 {
+		serror.Add(args[0].GetToken(), "Argument error", "Expected no arguments for breakpoint builtin")
+		serror.Panic()
+	}
+	if consts.DEBUGGER != nil {
+		consts.DEBUGGER.Breakpoint(tok)
+	}
+	return nil
+}
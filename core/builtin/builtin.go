@@ -6,22 +6,66 @@
 package builtin
 
 import (
+	"strings"
+
 	"github.com/xnacly/sophia/core/alloc"
 	"github.com/xnacly/sophia/core/consts"
 	"github.com/xnacly/sophia/core/types"
 )
 
-func init() {
-	builtins := map[string]types.KnownFunctionInterface{
-		"len":     builtinLen,
-		"map":     builtinMap,
-		"type":    builtinType,
-		"println": builtinPrintln,
-		"filter":  builtinFilter,
-		"assert":  builtinAssert,
+// Function is a built-in function: its implementation and the description of
+// its parameters used for static analysis and tooling
+type Function struct {
+	Params   []string // names of the parameters
+	Variadic bool     // the last parameter accepts zero or more arguments
+	Returns  types.Type
+	Doc      string
+	Func     types.KnownFunctionInterface
+}
+
+// reports whether the built-in accepts n arguments
+func (f Function) Accepts(n int) bool {
+	if f.Variadic {
+		return n >= len(f.Params)-1
 	}
+	return n == len(f.Params)
+}
 
-	for name, function := range builtins {
-		consts.FUNC_TABLE[alloc.NewFunc(name)] = function
+// formats the signature as a call of the built-in named name: (map fn iterable)
+func (f Function) Format(name string) string {
+	b := strings.Builder{}
+	b.WriteRune('(')
+	b.WriteString(name)
+	for i, p := range f.Params {
+		b.WriteRune(' ')
+		b.WriteString(p)
+		if f.Variadic && i+1 == len(f.Params) {
+			b.WriteString("...")
+		}
+	}
+	b.WriteRune(')')
+	return b.String()
+}
+
+// all built-in functions, keyed by their name
+var BUILTINS = map[string]Function{
+	"len":           {Params: []string{"value"}, Returns: types.ANY, Doc: "Returns the length of a string, array or object.", Func: builtinLen},
+	"map":           {Params: []string{"fn", "iterable"}, Returns: types.ARRAY, Doc: "Calls the lambda fn for each element of iterable and returns an array of the results.", Func: builtinMap},
+	"type":          {Params: []string{"value"}, Returns: types.STRING, Doc: "Returns the name of the type of value.", Func: builtinType},
+	"println":       {Params: []string{"values"}, Variadic: true, Returns: types.ANY, Doc: "Writes values separated by spaces and a trailing newline to stdout.", Func: builtinPrintln},
+	"filter":        {Params: []string{"fn", "iterable"}, Returns: types.ARRAY, Doc: "Returns an array of all elements of iterable the lambda fn returned true for.", Func: builtinFilter},
+	"assert":        {Params: []string{"condition"}, Returns: types.ANY, Doc: "Stops the execution if condition is not true.", Func: builtinAssert},
+	"assert-eq":     {Params: []string{"actual", "expected"}, Returns: types.ANY, Doc: "Stops the execution if actual is not equal to expected, arrays and objects are compared element wise.", Func: builtinAssertEq},
+	"assert-ne":     {Params: []string{"a", "b"}, Returns: types.ANY, Doc: "Stops the execution if a is equal to b.", Func: builtinAssertNe},
+	"assert-approx": {Params: []string{"actual", "expected", "epsilon"}, Variadic: true, Returns: types.ANY, Doc: "Stops the execution if the numbers actual and expected differ by more than epsilon, defaults to 1e-9.", Func: builtinAssertApprox},
+	"assert-throws": {Params: []string{"body"}, Variadic: true, Returns: types.ANY, Doc: "Stops the execution if evaluating body does not cause a runtime error.", Func: builtinAssertThrows},
+	"decimal":       {Params: []string{"value"}, Returns: types.DECIMAL, Doc: "Converts a float or string to an arbitrary precision decimal.", Func: builtinDecimal},
+	"breakpoint":    {Params: []string{}, Returns: types.ANY, Doc: "Pauses the evaluation if running via sophia debug, does nothing otherwise.", Func: builtinBreakpoint},
+	"test":          {Params: []string{"name", "body"}, Variadic: true, Returns: types.ANY, Doc: "Defines a test called name, its body is evaluated by sophia test and skipped otherwise.", Func: builtinTest},
+}
+
+func init() {
+	for name, f := range BUILTINS {
+		consts.FUNC_TABLE[alloc.NewFunc(name)] = f.Func
 	}
 }
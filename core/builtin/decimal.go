@@ -0,0 +1,36 @@
+package builtin
+
+import (
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+func builtinDecimal(tok *token.Token, args ...types.Node) any {
+	if len(args) != 1 {
+		serror.Add(tok, "Argument error", "Expected exactly 1 argument for decimal built-in")
+		serror.Panic()
+	}
+	switch v := args[0].Eval().(type) {
+	case types.Decimal:
+		return v
+	case float64:
+		d, ok := types.DecimalFromFloat(v)
+		if !ok {
+			serror.Add(args[0].GetToken(), "Type error", "Can't convert %v to a decimal, only finite floats can be converted", v)
+			serror.Panic()
+		}
+		return d
+	case string:
+		d, ok := types.NewDecimal(v)
+		if !ok {
+			serror.Add(args[0].GetToken(), "Type error", "Can't convert %q to a decimal", v)
+			serror.Panic()
+		}
+		return d
+	default:
+		serror.Add(args[0].GetToken(), "Type error", "Can't convert value of type %T to a decimal, expected string or float", v)
+		serror.Panic()
+	}
+	return nil
+}
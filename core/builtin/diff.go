@@ -0,0 +1,95 @@
+package builtin
+
+import (
+	"reflect"
+	"sort"
+	"strconv"
+
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/shared"
+	"github.com/xnacly/sophia/core/token"
+)
+
+// compares actual and expected structurally, returns a line for each
+// difference prefixed with the path of the differing element, such as
+// #[1]#["name"]: got "anon", expected "bob". Empty if the values are equal,
+// errors comparing the values are reported at t.
+func diff(actual, expected any, t *token.Token) []string {
+	return diffAt(actual, expected, t, "", make([]string, 0), map[[2]uintptr]bool{})
+}
+
+// seen contains the pairs of arrays and objects currently compared, pairs
+// compared again are part of a cycle and considered equal
+func diffAt(actual, expected any, t *token.Token, path string, res []string, seen map[[2]uintptr]bool) []string {
+	switch a := actual.(type) {
+	case []any:
+		e, ok := expected.([]any)
+		if !ok {
+			break
+		}
+		pair := [2]uintptr{reflect.ValueOf(a).Pointer(), reflect.ValueOf(e).Pointer()}
+		if len(a) != 0 && len(e) != 0 && seen[pair] {
+			return res
+		}
+		seen[pair] = true
+		defer delete(seen, pair)
+		for i := 0; i < max(len(a), len(e)); i++ {
+			p := path + "#[" + strconv.Itoa(i) + "]"
+			if i >= len(e) {
+				res = append(res, p+": unexpected "+shared.Repr(a[i]))
+			} else if i >= len(a) {
+				res = append(res, p+": missing "+shared.Repr(e[i]))
+			} else {
+				res = diffAt(a[i], e[i], t, p, res, seen)
+			}
+		}
+		return res
+	case map[string]any:
+		e, ok := expected.(map[string]any)
+		if !ok {
+			break
+		}
+		pair := [2]uintptr{reflect.ValueOf(a).Pointer(), reflect.ValueOf(e).Pointer()}
+		if seen[pair] {
+			return res
+		}
+		seen[pair] = true
+		defer delete(seen, pair)
+		keys := make([]string, 0, len(a)+len(e))
+		for k := range a {
+			keys = append(keys, k)
+		}
+		for k := range e {
+			if _, ok := a[k]; !ok {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			p := path + "#[" + shared.Repr(k) + "]"
+			av, inActual := a[k]
+			ev, inExpected := e[k]
+			if !inExpected {
+				res = append(res, p+": unexpected "+shared.Repr(av))
+			} else if !inActual {
+				res = append(res, p+": missing "+shared.Repr(ev))
+			} else {
+				res = diffAt(av, ev, t, p, res, seen)
+			}
+		}
+		return res
+	}
+	if isContainer(actual) || isContainer(expected) || !expr.Equals(actual, expected, t) {
+		res = append(res, path+": got "+shared.Repr(actual)+", expected "+shared.Repr(expected))
+	}
+	return res
+}
+
+// arrays and objects can not be compared via expr.Equals
+func isContainer(v any) bool {
+	switch v.(type) {
+	case []any, map[string]any:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,43 @@
+package builtin
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core/types"
+)
+
+func TestDiff(t *testing.T) {
+	one, _ := types.NewDecimal("1")
+	cyclic := []any{1.0, nil}
+	cyclic[1] = cyclic
+	tests := []struct {
+		name     string
+		actual   any
+		expected any
+		exp      []string
+	}{
+		{name: "equal floats", actual: 1.0, expected: 1.0, exp: []string{}},
+		{name: "float and decimal", actual: 1.0, expected: one, exp: []string{}},
+		{name: "different types", actual: "1", expected: 1.0, exp: []string{`: got "1", expected 1`}},
+		{name: "nested arrays", actual: []any{1.0, []any{2.0, 3.0}}, expected: []any{1.0, []any{2.0, 3.0}}, exp: []string{}},
+		{name: "array element", actual: []any{1.0, []any{2.0, 4.0}}, expected: []any{1.0, []any{2.0, 3.0}}, exp: []string{"#[1]#[1]: got 4, expected 3"}},
+		{name: "array length", actual: []any{1.0, 2.0}, expected: []any{1.0, 3.0, 4.0}, exp: []string{"#[1]: got 2, expected 3", "#[2]: missing 4"}},
+		{name: "array and object", actual: []any{}, expected: map[string]any{}, exp: []string{": got [], expected {}"}},
+		{
+			name:     "object keys",
+			actual:   map[string]any{"name": "anon", "age": 25.0},
+			expected: map[string]any{"name": "bob", "id": 1.0},
+			exp:      []string{`#["age"]: unexpected 25`, `#["id"]: missing 1`, `#["name"]: got "anon", expected "bob"`},
+		},
+		{name: "cycle", actual: cyclic, expected: cyclic, exp: []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := diff(test.actual, test.expected, nil)
+			if strings.Join(got, "\n") != strings.Join(test.exp, "\n") {
+				t.Errorf("wanted %q, got %q", test.exp, got)
+			}
+		})
+	}
+}
@@ -17,7 +17,7 @@ func builtinFilter(tok *token.Token, args ...types.Node) any {
 	switch args[0].(type) {
 	case *expr.Call, *expr.Lambda:
 	default:
-		serror.Add(args[0].GetToken(), "Argument Error", "Expected first argument to be a function call, got %T", args[0])
+		serror.Add(args[0].GetToken(), "Argument error", "Expected first argument to be a function call, got %T", args[0])
 		serror.Panic()
 	}
 
@@ -57,7 +57,7 @@ func builtinFilter(tok *token.Token, args ...types.Node) any {
 		}
 		r = t
 	default:
-		serror.Add(args[1].GetToken(), "Error", "Can't filter target of type %T, expected string or array", args[1])
+		serror.Add(args[1].GetToken(), "Type error", "Can't filter target of type %T, expected string or array", args[1])
 		serror.Panic()
 	}
 
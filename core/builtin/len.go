@@ -20,7 +20,7 @@ func builtinLen(tok *token.Token, args ...types.Node) any {
 	case []any:
 		return len(v)
 	default:
-		serror.Add(tok, "Error", "Can't compute length for target of type %T", v)
+		serror.Add(tok, "Type error", "Can't compute length for target of type %T", v)
 		serror.Panic()
 	}
 	return nil
@@ -17,7 +17,7 @@ func builtinMap(tok *token.Token, args ...types.Node) any {
 	switch args[0].(type) {
 	case *expr.Call, *expr.Lambda:
 	default:
-		serror.Add(args[0].GetToken(), "Argument Error", "Expected first argument to be a function call, got %T", args[0])
+		serror.Add(args[0].GetToken(), "Argument error", "Expected first argument to be a function call, got %T", args[0])
 		serror.Panic()
 	}
 
@@ -47,7 +47,7 @@ func builtinMap(tok *token.Token, args ...types.Node) any {
 		}
 		r = t
 	default:
-		serror.Add(args[1].GetToken(), "Error", "Can't map over target of type %T, expected string, array or object", args[1])
+		serror.Add(args[1].GetToken(), "Type error", "Can't map over target of type %T, expected string, array or object", args[1])
 		serror.Panic()
 	}
 
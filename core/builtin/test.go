@@ -0,0 +1,25 @@
+package builtin
+
+import (
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+func builtinTest(tok *token.Token, args ...types.Node) any {
+	if len(args) < 1 {
+		serror.Add(tok, "Argument error", "Expected the name of the test as first argument for test builtin")
+		serror.Panic()
+	}
+	name, ok := args[0].(*expr.String)
+	if !ok {
+		serror.Add(args[0].GetToken(), "Argument error", "Expected the name of the test to be a string, got %T", args[0])
+		serror.Panic()
+	}
+	if consts.COLLECT_TESTS {
+		consts.TESTS = append(consts.TESTS, consts.Test{Name: name.Token.Raw, Token: tok, Body: args[1:]})
+	}
+	return nil
+}
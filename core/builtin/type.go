@@ -24,6 +24,8 @@ func builtinType(tok *token.Token, args ...types.Node) any {
 		return "object"
 	case float64:
 		return "float"
+	case types.Decimal:
+		return "decimal"
 	case string:
 		return "string"
 	default:
@@ -0,0 +1,309 @@
+// Statically analyses the abstract syntax tree for errors that would otherwise
+// only be detected while evaluating, such as undefined variables and calls to
+// unknown functions, as well as for suspicious code, such as unused variables.
+// All findings are reported via serror.
+package checker
+
+import (
+	"github.com/xnacly/sophia/core/alloc"
+	"github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/types"
+)
+
+type variable struct {
+	ident *expr.Ident
+	used  bool
+	// parameters of functions and loops are not reported if unused
+	param bool
+}
+
+type scope struct {
+	parent *scope
+	vars   map[string]*variable
+	// variables defined via let inside of functions and lambdas are stored
+	// in the innermost function scope, loops only scope their parameter
+	function bool
+	// order of definition, for deterministic reports
+	order []*variable
+}
+
+func (s *scope) lookup(name string) (*variable, *scope) {
+	for sc := s; sc != nil; sc = sc.parent {
+		if v, ok := sc.vars[name]; ok {
+			return v, sc
+		}
+	}
+	return nil, nil
+}
+
+type checker struct {
+	scope     *scope
+	functions map[string]*expr.Func
+	// variables defined outside of functions, functions may use globals
+	// defined after the function definition
+	globals map[string]bool
+	// globals used inside of functions before their definition
+	hoisted map[string]bool
+	depth   int // nesting of functions and lambdas
+}
+
+// Check reports undefined variables, calls to unknown functions, calls with
+// the wrong amount of arguments, unused variables, variables shadowing other
+// variables and unreachable code after return statements
+func Check(ast []types.Node) {
+	c := &checker{
+		scope:     &scope{vars: map[string]*variable{}, function: true},
+		functions: map[string]*expr.Func{},
+		globals:   map[string]bool{},
+		hoisted:   map[string]bool{},
+	}
+	for _, n := range ast {
+		c.collect(n, false)
+	}
+	c.body(ast)
+	c.unused(c.scope)
+}
+
+// collects all function definitions and all variables defined outside of
+// functions
+func (c *checker) collect(n types.Node, inFunction bool) {
+	switch n := n.(type) {
+	case nil:
+		return
+	case *expr.Func:
+		if ident, ok := n.Name.(*expr.Ident); ok {
+			c.functions[ident.Name] = n
+		}
+		inFunction = true
+	case *expr.Lambda:
+		inFunction = true
+	case *expr.Var:
+		if !inFunction && !n.IndexAssign {
+			c.globals[n.Ident.Name] = true
+		}
+	}
+	for _, child := range Children(n) {
+		c.collect(child, inFunction)
+	}
+}
+
+// Children returns all child nodes of n, including conditions, loop targets
+// and the bodies of functions
+func Children(n types.Node) []types.Node {
+	switch n := n.(type) {
+	case *expr.If:
+		return append([]types.Node{n.Condition}, n.Body...)
+	case *expr.For:
+		return append([]types.Node{n.LoopOver}, n.Body...)
+	case *expr.Lambda:
+		return n.Body
+	case *expr.Index:
+		return append([]types.Node{n.Target}, n.Index...)
+	case *expr.Object:
+		values := make([]types.Node, 0, len(n.Children))
+		for _, pair := range n.Children {
+			values = append(values, pair.Value)
+		}
+		return values
+	case *expr.Return:
+		if n.Child == nil {
+			return nil
+		}
+	}
+	return n.GetChildren()
+}
+
+// checks a list of statements, statements following a return are unreachable
+func (c *checker) body(nodes []types.Node) {
+	for i, n := range nodes {
+		c.node(n)
+		if _, ok := n.(*expr.Return); ok && i+1 < len(nodes) {
+			serror.AddNode(nodes[i+1], "Unreachable code", "Statements after a return statement are never executed.")
+			for _, unreachable := range nodes[i+1:] {
+				c.node(unreachable)
+			}
+			return
+		}
+	}
+}
+
+func (c *checker) node(n types.Node) {
+	switch n := n.(type) {
+	case nil:
+		return
+	case *expr.Ident:
+		c.use(n)
+	case *expr.Var:
+		c.body(n.Value)
+		if n.IndexAssign {
+			c.use(n.Ident)
+			return
+		}
+		c.define(n.Ident, false)
+	case *expr.Func:
+		params := []types.Node{}
+		if n.Params != nil {
+			params = n.Params.Children
+		}
+		c.function(params, n.Body)
+	case *expr.Lambda:
+		params := []types.Node{}
+		if n.Params != nil {
+			params = n.Params.Children
+		}
+		c.function(params, n.Body)
+	case *expr.For:
+		c.node(n.LoopOver)
+		c.push(false)
+		if n.Params != nil {
+			for _, p := range n.Params.Children {
+				if ident, ok := p.(*expr.Ident); ok {
+					c.define(ident, true)
+				}
+			}
+		}
+		c.body(n.Body)
+		c.pop()
+	case *expr.If:
+		c.node(n.Condition)
+		c.body(n.Body)
+	case *expr.Match:
+		c.body(n.Branches)
+	case *expr.Module:
+		c.body(n.Children)
+	case *expr.Use:
+		// modules are resolved while evaluating
+	case *expr.Call:
+		c.call(n)
+		c.body(n.Args)
+	default:
+		for _, child := range Children(n) {
+			c.node(child)
+		}
+	}
+}
+
+// checks a function or lambda with the given parameters and body
+func (c *checker) function(params []types.Node, body []types.Node) {
+	c.depth++
+	c.push(true)
+	for _, p := range params {
+		if ident, ok := p.(*expr.Ident); ok {
+			c.define(ident, true)
+		}
+	}
+	c.body(body)
+	c.pop()
+	c.depth--
+}
+
+// checks if the called function exists and accepts the amount of arguments
+func (c *checker) call(n *expr.Call) {
+	name := n.Token.Raw
+	if def, ok := c.functions[name]; ok {
+		if def.Params == nil {
+			return
+		}
+		wanted, got := len(def.Params.Children), len(n.Args)
+		var err *serror.Error
+		if got > wanted {
+			err = serror.AddNode(n, "Too many arguments", "Too many arguments for %q, wanted %d, got %d", name, wanted, got)
+		} else if got < wanted {
+			err = serror.AddNode(n, "Not enough arguments", "Not enough arguments for %q, wanted %d, got %d", name, wanted, got)
+		}
+		if err != nil {
+			err.Label(def.Params.GetSpan(), "%q defined here", name)
+		}
+		return
+	}
+	if sig, ok := builtin.BUILTINS[name]; ok {
+		if !sig.Accepts(len(n.Args)) {
+			serror.AddNode(n, "Argument error", "Wrong amount of arguments for %q, expected %s, got %d", name, sig.Format(name), len(n.Args))
+		}
+		return
+	}
+	// functions registered via the embedding api
+	if _, ok := consts.FUNC_TABLE[alloc.Default.Functions[name]]; ok {
+		return
+	}
+	candidates := make([]string, 0, len(c.functions)+len(builtin.BUILTINS))
+	for f := range c.functions {
+		candidates = append(candidates, f)
+	}
+	for f := range builtin.BUILTINS {
+		candidates = append(candidates, f)
+	}
+	serror.Add(n.Token, "Undefined function", "Function %q not defined", name).Suggest(name, candidates)
+}
+
+// marks the variable referenced by ident as used, reports undefined variables
+func (c *checker) use(ident *expr.Ident) {
+	if v, _ := c.scope.lookup(ident.Name); v != nil {
+		v.used = true
+		return
+	}
+	if c.depth > 0 && c.globals[ident.Name] {
+		c.hoisted[ident.Name] = true
+		return
+	}
+	candidates := make([]string, 0)
+	for sc := c.scope; sc != nil; sc = sc.parent {
+		for name := range sc.vars {
+			candidates = append(candidates, name)
+		}
+	}
+	if c.depth > 0 {
+		for name := range c.globals {
+			candidates = append(candidates, name)
+		}
+	}
+	serror.Add(ident.Token, "Undefined variable", "Variable %q is not defined.", ident.Name).Suggest(ident.Name, candidates)
+}
+
+// defines the variable ident in the current scope, variables defined via let
+// are stored in the innermost function scope
+func (c *checker) define(ident *expr.Ident, param bool) {
+	target := c.scope
+	if !param {
+		for !target.function {
+			target = target.parent
+		}
+	}
+	if _, ok := target.vars[ident.Name]; ok {
+		// assigning a new value to an existing variable
+		return
+	}
+	if outer, _ := c.scope.lookup(ident.Name); outer != nil {
+		serror.Add(ident.Token, "Shadowed variable", "%q shadows the variable defined in line %d.", ident.Name, outer.ident.Token.Line+1).
+			Label(outer.ident.Token.Span(), "%q previously defined here", ident.Name)
+	}
+	v := &variable{ident: ident, param: param}
+	target.vars[ident.Name] = v
+	target.order = append(target.order, v)
+}
+
+func (c *checker) push(function bool) {
+	c.scope = &scope{
+		parent:   c.scope,
+		vars:     map[string]*variable{},
+		function: function,
+	}
+}
+
+func (c *checker) pop() {
+	c.unused(c.scope)
+	c.scope = c.scope.parent
+}
+
+// reports variables defined but never used in s
+func (c *checker) unused(s *scope) {
+	for _, v := range s.order {
+		if v.used || v.param || (s.parent == nil && c.hoisted[v.ident.Name]) {
+			continue
+		}
+		serror.Add(v.ident.Token, "Unused variable", "Variable %q is defined but never used.", v.ident.Name)
+	}
+}
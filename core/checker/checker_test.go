@@ -0,0 +1,88 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	_ "github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/parser"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+func TestCheck(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp []string
+	}{
+		{in: `(let a 1)(println a)`, exp: []string{}},
+		{in: `(println a)`, exp: []string{"Undefined variable"}},
+		{in: `(println (+ a 1))(let a 1)`, exp: []string{"Undefined variable", "Unused variable"}},
+		{in: `(fun f [] (println g))(let g 1)(f)`, exp: []string{}},
+		{in: `(fun f [n] (+ n 1))(println (f 1) n)`, exp: []string{"Undefined variable"}},
+		{in: `(unknown 1)`, exp: []string{"Undefined function"}},
+		{in: `(fun f [a b] a)(f 1)`, exp: []string{"Not enough arguments"}},
+		{in: `(fun f [a] a)(f 1 2)`, exp: []string{"Too many arguments"}},
+		{in: `(println (len "a" "b"))`, exp: []string{"Argument error"}},
+		{in: `(println (map (lambda [x] (* x 2)) [1 2]))`, exp: []string{}},
+		{in: `(let a 1)`, exp: []string{"Unused variable"}},
+		{in: `(fun f [] (let x 1) 2)(f)`, exp: []string{"Unused variable"}},
+		{in: `(let c 0)(for [i] 3 (let c (+ c i)))(println c)`, exp: []string{}},
+		{in: `(let n 1)(fun f [n] n)(println (f n))`, exp: []string{"Shadowed variable"}},
+		{in: `(for [i] 2 (for [i] 2 (println i)))`, exp: []string{"Shadowed variable"}},
+		{in: `(fun f [a] (return a) (println a))(f 1)`, exp: []string{"Unreachable code"}},
+		{in: `(fun f [a] (if a (return 1)) 2)(f true)`, exp: []string{}},
+		{in: `(let o {name: "anon"})(println o#["name"])`, exp: []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, test.in, "test", nil))
+			ast := parser.New(lexer.New(strings.NewReader(test.in), "test").Lex(), "test").Parse()
+			if serror.HasErrors() {
+				t.Fatalf("failed to parse %q", test.in)
+			}
+			Check(ast)
+			errs := serror.Default().Errors()
+			if len(errs) != len(test.exp) {
+				t.Fatalf("wanted %d errors, got %d: %v", len(test.exp), len(errs), errs)
+			}
+			for i, e := range errs {
+				if e.Title != test.exp[i] {
+					t.Errorf("wanted error %q, got %q: %s", test.exp[i], e.Title, e.Info)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckHelp(t *testing.T) {
+	tests := []struct {
+		in    string
+		help  string
+		label bool
+	}{
+		{in: `(let value 1)(println valeu)`, help: "did you mean `value`?"},
+		{in: `(printn 1)`, help: "did you mean `println`?"},
+		{in: `(fun square [n] n)(sqaure 1)`, help: "did you mean `square`?"},
+		{in: `(fun f [a] a)(f 1 2)`, label: true},
+		{in: `(let n 1)(fun f [n] n)(println (f n))`, label: true},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, test.in, "test", nil))
+			ast := parser.New(lexer.New(strings.NewReader(test.in), "test").Lex(), "test").Parse()
+			Check(ast)
+			errs := serror.Default().Errors()
+			if len(errs) == 0 {
+				t.Fatalf("expected errors")
+			}
+			if errs[0].Help != test.help {
+				t.Errorf("wanted help %q, got %q", test.help, errs[0].Help)
+			}
+			if test.label && len(errs[0].Labels) == 0 {
+				t.Errorf("expected a label pointing at the definition")
+			}
+		})
+	}
+}
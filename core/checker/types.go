@@ -0,0 +1,454 @@
+package checker
+
+import (
+	"github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+// maximum amount of passes for inferring the types of variables, variables
+// may depend on variables assigned later in the source, such as in loops
+const MAX_INFERENCE_PASSES = 8
+
+// unknown is the type of values not yet inferred, it is treated as
+// types.ANY once all passes are done
+const unknown types.Type = ""
+
+// a variable, identified by its key and the function, lambda or loop
+// defining it, nil for variables defined at the top level. Variables of
+// different functions share their key if they share their name.
+type typedVar struct {
+	scope types.Node
+	key   uint32
+}
+
+// variables defined by a function, lambda or loop
+type typeScope struct {
+	node types.Node
+	keys map[uint32]bool
+}
+
+type typeChecker struct {
+	// inferred types of variables, variables assigned values of different
+	// types are of type any
+	vars map[typedVar]types.Type
+	// types of variables annotated in their definition: (let a:float 1)
+	declared  map[typedVar]types.Type
+	functions map[string]*expr.Func
+	// functions, lambdas and loops enclosing the currently checked node
+	scopes []typeScope
+	// declared return types of the functions currently checked
+	returns []types.Type
+	changed bool
+	// errors are only reported in the last pass, once all types are inferred
+	report bool
+}
+
+// CheckTypes infers the types of all expressions and variables, reports values
+// of the wrong type, such as adding a string to a float or indexing a float,
+// as well as values not matching their type annotation. Values of unknown
+// type are never reported. Returns the inferred types of all variables
+// defined outside of functions, keyed by the variable key.
+func CheckTypes(ast []types.Node) map[uint32]types.Type {
+	c := &typeChecker{
+		vars:      map[typedVar]types.Type{},
+		declared:  map[typedVar]types.Type{},
+		functions: map[string]*expr.Func{},
+	}
+	c.collect(ast, nil)
+	for i := 0; i < MAX_INFERENCE_PASSES; i++ {
+		c.changed = false
+		c.body(ast)
+		if !c.changed {
+			break
+		}
+	}
+	c.report = true
+	c.body(ast)
+	res := map[uint32]types.Type{}
+	for v, t := range c.vars {
+		if v.scope != nil {
+			continue
+		}
+		if t == unknown {
+			t = types.ANY
+		}
+		res[v.key] = t
+	}
+	return res
+}
+
+// collects functions and the declared types of annotated variables, scope is
+// the function or lambda enclosing nodes
+func (c *typeChecker) collect(nodes []types.Node, scope types.Node) {
+	for _, n := range nodes {
+		inner := scope
+		switch n := n.(type) {
+		case nil:
+			continue
+		case *expr.Func:
+			if ident, ok := n.Name.(*expr.Ident); ok {
+				c.functions[ident.Name] = n
+			}
+			inner = n
+		case *expr.Lambda:
+			inner = n
+		case *expr.Var:
+			if !n.IndexAssign && n.Ident.Type != nil {
+				v := typedVar{scope, n.Ident.Key}
+				t := types.Type(n.Ident.Type.Raw)
+				c.declared[v] = t
+				c.vars[v] = t
+			}
+		}
+		c.collect(Children(n), inner)
+	}
+}
+
+// returns the variable key refers to in the current scope: the parameter or
+// variable of the innermost function, lambda or loop defining it, otherwise
+// the variable defined at the top level
+func (c *typeChecker) resolve(key uint32) typedVar {
+	for i := len(c.scopes) - 1; i >= 0; i-- {
+		if c.scopes[i].keys[key] {
+			return typedVar{c.scopes[i].node, key}
+		}
+	}
+	return typedVar{nil, key}
+}
+
+func (c *typeChecker) body(nodes []types.Node) types.Type {
+	t := types.ANY
+	for _, n := range nodes {
+		t = c.infer(n)
+	}
+	return t
+}
+
+// returns the type of the value n evaluates to
+func (c *typeChecker) infer(n types.Node) types.Type {
+	switch n := n.(type) {
+	case nil:
+		return types.ANY
+	case *expr.Float:
+		return types.FLOAT
+	case *expr.Decimal:
+		return types.DECIMAL
+	case *expr.String:
+		return types.STRING
+	case *expr.Boolean:
+		return types.BOOL
+	case *expr.TemplateString, *expr.Format:
+		c.body(n.GetChildren())
+		return types.STRING
+	case *expr.Array:
+		c.body(n.Children)
+		return types.ARRAY
+	case *expr.Object:
+		c.body(Children(n))
+		return types.OBJECT
+	case *expr.Ident:
+		if n.Type != nil && c.report {
+			serror.AddNode(n, "Unexpected type annotation", "Type annotations are only allowed for variable definitions, function names and function parameters.")
+		}
+		return c.vars[c.resolve(n.Key)]
+	case *expr.Var:
+		return c.variable(n)
+	case *expr.Func:
+		c.function(n)
+		return types.ANY
+	case *expr.Lambda:
+		c.scoped(n, n.Params, n.Body, func() {
+			c.returns = append(c.returns, types.ANY)
+			c.body(n.Body)
+			c.returns = c.returns[:len(c.returns)-1]
+		})
+		return types.ANY
+	case *expr.Return:
+		t := c.infer(n.Child)
+		if len(c.returns) != 0 && n.Child != nil {
+			c.expect(n.Child, t, c.returns[len(c.returns)-1])
+		}
+		return t
+	case *expr.For:
+		c.loop(n)
+		return types.ANY
+	case *expr.If:
+		c.expect(n.Condition, c.infer(n.Condition), types.BOOL)
+		c.body(n.Body)
+		return types.ANY
+	case *expr.Add, *expr.Sub, *expr.Mul, *expr.Div, *expr.Mod:
+		return c.arithmetic(n.GetChildren())
+	case *expr.Lt, *expr.Gt:
+		c.arithmetic(n.GetChildren())
+		return types.BOOL
+	case *expr.And, *expr.Or:
+		for _, child := range n.GetChildren() {
+			c.expect(child, c.infer(child), types.BOOL)
+		}
+		return types.BOOL
+	case *expr.Equal:
+		c.body(n.Children)
+		return types.BOOL
+	case *expr.Neg:
+		t := c.infer(n.Children)
+		switch t {
+		case types.FLOAT, types.DECIMAL, types.BOOL:
+			return t
+		case unknown, types.ANY:
+			return t
+		}
+		if c.report {
+			serror.AddNode(n.Children, "Type error", "Expected value of type float, decimal or bool, got %s", t)
+		}
+		return types.ANY
+	case *expr.Merge:
+		return c.merge(n)
+	case *expr.Index:
+		c.index(n)
+		return types.ANY
+	case *expr.Call:
+		return c.call(n)
+	default:
+		c.body(Children(n))
+		return types.ANY
+	}
+}
+
+// reports the node n of type got if it is not of type wanted, values of
+// unknown type are not reported. Returns the reported error, nil if n is of
+// the wanted type.
+func (c *typeChecker) expect(n types.Node, got types.Type, wanted types.Type) *serror.Error {
+	if !c.report || got == unknown || got == types.ANY || wanted == types.ANY || got == wanted {
+		return nil
+	}
+	return serror.AddNode(n, "Type error", "Expected value of type %s, got %s", wanted, got)
+}
+
+// reports the node n of type got if it is not of the type annotated by
+// annotation, the error points at the annotation
+func (c *typeChecker) expectAnnotated(n types.Node, got types.Type, annotation *token.Token) {
+	if err := c.expect(n, got, types.Type(annotation.Raw)); err != nil {
+		err.Label(annotation.Span(), "expected because of this annotation")
+	}
+}
+
+// assigns a value of type t to the variable v
+func (c *typeChecker) assign(v typedVar, t types.Type) {
+	if _, ok := c.declared[v]; ok || t == unknown {
+		return
+	}
+	old := c.vars[v]
+	joined := t
+	if old != unknown && old != t {
+		joined = types.ANY
+	}
+	if joined != old {
+		c.vars[v] = joined
+		c.changed = true
+	}
+}
+
+func (c *typeChecker) variable(n *expr.Var) types.Type {
+	var t types.Type
+	switch len(n.Value) {
+	case 0:
+		t = types.ANY
+	case 1:
+		t = c.infer(n.Value[0])
+	default:
+		// (let a 1 2 3) defines an array
+		c.body(n.Value)
+		t = types.ARRAY
+	}
+	if n.IndexAssign {
+		return t
+	}
+	v := c.resolve(n.Ident.Key)
+	if declared, ok := c.declared[v]; ok {
+		var value types.Node = n
+		if len(n.Value) == 1 {
+			value = n.Value[0]
+		}
+		if n.Ident.Type != nil {
+			c.expectAnnotated(value, t, n.Ident.Type)
+		} else {
+			// assigning to a variable annotated in its definition
+			c.expect(value, t, declared)
+		}
+		return declared
+	}
+	c.assign(v, t)
+	return t
+}
+
+// checks f in the scope of node, defining its parameters and the variables
+// defined in body. Parameters are of type any if not annotated.
+func (c *typeChecker) scoped(node types.Node, params *expr.Array, body []types.Node, f func()) {
+	keys := map[uint32]bool{}
+	if params != nil {
+		for _, p := range params.Children {
+			ident, ok := p.(*expr.Ident)
+			if !ok {
+				continue
+			}
+			keys[ident.Key] = true
+			t := types.ANY
+			if ident.Type != nil {
+				t = types.Type(ident.Type.Raw)
+			}
+			c.vars[typedVar{node, ident.Key}] = t
+		}
+	}
+	definitions(body, keys)
+	c.scopes = append(c.scopes, typeScope{node, keys})
+	f()
+	c.scopes = c.scopes[:len(c.scopes)-1]
+}
+
+// adds the keys of the variables defined in nodes to keys, excluding those
+// defined by nested functions and lambdas
+func definitions(nodes []types.Node, keys map[uint32]bool) {
+	for _, n := range nodes {
+		switch n := n.(type) {
+		case nil, *expr.Func, *expr.Lambda:
+			continue
+		case *expr.Var:
+			if !n.IndexAssign {
+				keys[n.Ident.Key] = true
+			}
+		}
+		definitions(Children(n), keys)
+	}
+}
+
+func (c *typeChecker) function(n *expr.Func) {
+	returns := types.ANY
+	if ident, ok := n.Name.(*expr.Ident); ok && ident.Type != nil {
+		returns = types.Type(ident.Type.Raw)
+	}
+	c.scoped(n, n.Params, n.Body, func() {
+		c.returns = append(c.returns, returns)
+		t := c.body(n.Body)
+		if len(n.Body) != 0 {
+			// the value of the last statement is returned
+			last := n.Body[len(n.Body)-1]
+			if _, ok := last.(*expr.Return); !ok {
+				c.expect(last, t, returns)
+			}
+		}
+		c.returns = c.returns[:len(c.returns)-1]
+	})
+}
+
+func (c *typeChecker) loop(n *expr.For) {
+	t := c.infer(n.LoopOver)
+	if c.report && t != unknown && t != types.ANY && t != types.ARRAY && t != types.FLOAT {
+		serror.AddNode(n.LoopOver, "Invalid iterator", "expected container or upper bound for iteration, got: %s", t)
+	}
+	if n.Params == nil {
+		c.body(n.Body)
+		return
+	}
+	for _, p := range n.Params.Children {
+		if ident, ok := p.(*expr.Ident); ok && ident.Type != nil && c.report {
+			serror.AddNode(ident, "Unexpected type annotation", "Type annotations are only allowed for variable definitions, function names and function parameters.")
+		}
+	}
+	c.scoped(n, n.Params, nil, func() {
+		if t == types.FLOAT && len(n.Params.Children) != 0 {
+			// iterating up to a number
+			if ident, ok := n.Params.Children[0].(*expr.Ident); ok {
+				c.vars[typedVar{n, ident.Key}] = types.FLOAT
+			}
+		}
+		c.body(n.Body)
+	})
+}
+
+// checks the operands of arithmetic operations and comparisons
+func (c *typeChecker) arithmetic(children []types.Node) types.Type {
+	result := types.FLOAT
+	for _, child := range children {
+		t := c.infer(child)
+		switch {
+		case t == types.FLOAT:
+		case t == types.DECIMAL:
+			result = types.DECIMAL
+		case t == unknown:
+			if result == types.FLOAT {
+				result = unknown
+			}
+		case t == types.ANY:
+			if result != types.DECIMAL {
+				result = types.ANY
+			}
+		default:
+			if c.report {
+				serror.AddNode(child, "Type error", "Expected value of type float or decimal, got %s", t)
+			}
+			if result != types.DECIMAL {
+				result = types.ANY
+			}
+		}
+	}
+	return result
+}
+
+// strings are concatenated, all other values are merged into an array
+func (c *typeChecker) merge(n *expr.Merge) types.Type {
+	if len(n.Children) == 1 {
+		c.infer(n.Children[0])
+		return types.ARRAY
+	}
+	result := types.STRING
+	for _, child := range n.Children {
+		switch t := c.infer(child); t {
+		case types.STRING:
+		case unknown, types.ANY:
+			if result == types.STRING {
+				result = t
+			}
+		default:
+			result = types.ARRAY
+		}
+	}
+	return result
+}
+
+func (c *typeChecker) index(n *expr.Index) {
+	t := c.infer(n.Target)
+	if !c.report || t == unknown || t == types.ANY || t == types.ARRAY || t == types.OBJECT {
+		return
+	}
+	serror.AddNode(n.Target, "Index error", "Can't index value of type %s, expected array or object", t)
+}
+
+func (c *typeChecker) call(n *expr.Call) types.Type {
+	name := n.Token.Raw
+	argTypes := make([]types.Type, len(n.Args))
+	for i, arg := range n.Args {
+		argTypes[i] = c.infer(arg)
+	}
+	if def, ok := c.functions[name]; ok {
+		if def.Params != nil {
+			for i, p := range def.Params.Children {
+				ident, ok := p.(*expr.Ident)
+				if !ok || ident.Type == nil || i >= len(n.Args) {
+					continue
+				}
+				c.expectAnnotated(n.Args[i], argTypes[i], ident.Type)
+			}
+		}
+		if ident, ok := def.Name.(*expr.Ident); ok && ident.Type != nil {
+			return types.Type(ident.Type.Raw)
+		}
+		return types.ANY
+	}
+	if sig, ok := builtin.BUILTINS[name]; ok {
+		return sig.Returns
+	}
+	return types.ANY
+}
@@ -0,0 +1,67 @@
+package checker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/parser"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+func TestCheckTypes(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp []string
+	}{
+		{in: `(println (+ 1 2.5 0.1d))`, exp: []string{}},
+		{in: `(println (+ 1 "a"))`, exp: []string{"Type error"}},
+		{in: `(let a "a")(println (* a 2))`, exp: []string{"Type error"}},
+		{in: `(let a 1)(let b (+ a 1))(println (- b "c"))`, exp: []string{"Type error"}},
+		{in: `(let a 1)(println (% 0.5d a))`, exp: []string{}},
+		{in: `(println (% 5d "a"))`, exp: []string{"Type error"}},
+		{in: `(let a 1)(println a#[0])`, exp: []string{"Index error"}},
+		{in: `(let a [1 2])(println a#[0])`, exp: []string{}},
+		{in: `(if 1 (println "a"))`, exp: []string{"Type error"}},
+		{in: `(println (and true "a"))`, exp: []string{"Type error"}},
+		{in: `(for [i] "abc" (println i))`, exp: []string{"Invalid iterator"}},
+		{in: `(let a:float 1)(println a)`, exp: []string{}},
+		{in: `(let a:string 1)`, exp: []string{"Type error"}},
+		{in: `(let a:float 1)(let a "b")`, exp: []string{"Type error"}},
+		{in: `(let a:any 1)(let a "b")`, exp: []string{}},
+		{in: `(fun f:float [a:float] (* a 2))(println (f 1))`, exp: []string{}},
+		{in: `(fun f [a:float] a)(println (f "a"))`, exp: []string{"Type error"}},
+		{in: `(fun f:string [a:float] (+ a 1))(println (f 1))`, exp: []string{"Type error"}},
+		{in: `(fun f:string [a] (if a (return 1)) "b")(println (f true))`, exp: []string{"Type error"}},
+		{in: `(fun f:string [] "a")(println (+ (f) 1))`, exp: []string{"Type error"}},
+		{in: `(fun f [a] (+ a 1))(println (f "a"))`, exp: []string{}},
+		{in: `(let a 1)(let a "b")(println (+ a 1))`, exp: []string{}},
+		{in: `(println (++ "a" "b") (- (++ "a" "b") 1))`, exp: []string{"Type error"}},
+		{in: `(println (+ 1 (decimal "1.5")) (+ 1 (type 1)))`, exp: []string{"Type error"}},
+		{in: `(let a 1)(println a:float)`, exp: []string{"Unexpected type annotation"}},
+		{in: `(fun f [] (let x:float 1) x)(fun g [] (let x "a") x)(println (f) (g))`, exp: []string{}},
+		{in: `(fun f [] (let x 1) x)(fun g [] (let x "a") (* x 2))(println (f) (g))`, exp: []string{"Type error"}},
+		{in: `(let n "a")(fun f [n] (* n 2))(println (f 1) (++ n "b"))`, exp: []string{}},
+		{in: `(let l (lambda [x] (let y "a") y))(let y 1)(println (+ y 1) l)`, exp: []string{}},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, test.in, "test", nil))
+			ast := parser.New(lexer.New(strings.NewReader(test.in), "test").Lex(), "test").Parse()
+			if serror.HasErrors() {
+				t.Fatalf("failed to parse %q: %v", test.in, serror.Default().Errors())
+			}
+			CheckTypes(ast)
+			errs := serror.Default().Errors()
+			if len(errs) != len(test.exp) {
+				t.Fatalf("wanted %d errors, got %d: %v", len(test.exp), len(errs), errs)
+			}
+			for i, e := range errs {
+				if e.Title != test.exp[i] {
+					t.Errorf("wanted error %q, got %q: %s", test.exp[i], e.Title, e.Info)
+				}
+			}
+		})
+	}
+}
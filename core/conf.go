@@ -15,6 +15,14 @@ const ASCII_ART = `
 type Config struct {
 	AllErrors bool
 	Debug     bool // enable debug logs
+	// format errors are displayed in: text, json or sarif, text if empty
+	ErrorFormat string
+	// infer and check types before evaluating, skipping the evaluation if
+	// values of the wrong type are found
+	TypeCheck bool
+	// resolve relative paths of load statements against the directory of
+	// the loading file instead of the working directory, set by sophia test
+	LoadRelative bool
 }
 
 var CONF = Config{
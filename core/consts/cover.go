@@ -0,0 +1,11 @@
+package consts
+
+import "github.com/xnacly/sophia/core/types"
+
+// statements are only counted in COVERAGE if COVER is set, see sophia run
+// -cover and sophia test -cover
+var COVER = false
+
+// amount of evaluations of each statement registered via cover.Register,
+// see core/cover
+var COVERAGE = make(map[types.Node]int, 64)
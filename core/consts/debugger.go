@@ -0,0 +1,17 @@
+package consts
+
+import (
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+// pauses the evaluation, see sophia debug
+type Debugger interface {
+	// called before evaluating each statement
+	Statement(n types.Node)
+	// called by the breakpoint built-in at tok
+	Breakpoint(tok *token.Token)
+}
+
+// debugger attached to the evaluation, nil if not debugging
+var DEBUGGER Debugger
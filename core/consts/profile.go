@@ -0,0 +1,5 @@
+package consts
+
+// functions and statements are measured by core/profile if PROFILE is set,
+// see the -profile flag
+var PROFILE = false
@@ -0,0 +1,21 @@
+package consts
+
+import (
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+// function, lambda or built-in currently evaluated
+type Frame struct {
+	Name string
+	// token calling the function, for lambdas called by built-ins the
+	// lambda itself
+	Call    *token.Token
+	Builtin bool
+	// parameters of the function, nil for built-ins
+	Params []types.Node
+}
+
+// frames of all functions currently evaluated, the innermost last. Attached
+// to runtime errors as stack trace, see serror.Panic
+var CALL_STACK = make([]Frame, 0, 64)
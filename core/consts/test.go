@@ -0,0 +1,19 @@
+package consts
+
+import (
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+// test block defined via (test "name" body...)
+type Test struct {
+	Name  string
+	Token *token.Token
+	Body  []types.Node
+}
+
+// test blocks are only collected into TESTS while evaluating test files via
+// sophia test, otherwise they are skipped
+var COLLECT_TESTS = false
+
+var TESTS = make([]Test, 0, 16)
@@ -0,0 +1,219 @@
+// cover reports which statements of sophia source code were evaluated. The
+// statements of a program are registered before its evaluation, the
+// evaluator counts each evaluated statement in consts.COVERAGE while
+// consts.COVER is set.
+//
+// Coverage is reported per line: a line is covered if a statement starting
+// on it was evaluated and partially covered if another statement starting
+// on it was not evaluated, such as the body of a single line if.
+package cover
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xnacly/sophia/core/checker"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/types"
+)
+
+// registers the statements of ast, statements never evaluated are reported
+// as not covered. Test blocks are skipped, they are only evaluated by sophia
+// test and measure the coverage of the code they test.
+func Register(ast []types.Node) {
+	for _, n := range ast {
+		if c, ok := n.(*expr.Call); ok && c.Token.Raw == "test" {
+			continue
+		}
+		statement(n)
+		register(n)
+	}
+}
+
+// registers the statements nested in n, statements are the nodes the
+// evaluator counts: the top level and the bodies of functions, lambdas,
+// loops, ifs and the branches of matches
+func register(n types.Node) {
+	if n == nil {
+		return
+	}
+	var body []types.Node
+	switch n := n.(type) {
+	case *expr.Func:
+		body = n.Body
+	case *expr.Lambda:
+		body = n.Body
+	case *expr.For:
+		body = n.Body
+	case *expr.If:
+		body = n.Body
+	case *expr.Match:
+		body = n.Branches
+	}
+	for _, s := range body {
+		statement(s)
+	}
+	for _, c := range checker.Children(n) {
+		register(c)
+	}
+}
+
+func statement(n types.Node) {
+	if _, ok := consts.COVERAGE[n]; !ok {
+		consts.COVERAGE[n] = 0
+	}
+}
+
+// coverage of a source file
+type File struct {
+	Name       string
+	Statements int
+	Covered    int
+	// keyed by the zero based line number, lines without statements are
+	// omitted
+	Lines map[int]*Line
+}
+
+type Line struct {
+	// evaluations of the most evaluated statement starting on the line
+	Hits int
+	// a statement starting on the line was never evaluated
+	Missed bool
+}
+
+// returns the percentage of covered statements, 0 if there are none
+func (f *File) Percent() float64 {
+	if f.Statements == 0 {
+		return 0
+	}
+	return float64(f.Covered) / float64(f.Statements) * 100
+}
+
+// returns the coverage of all files containing registered statements, sorted
+// by name. Statements of files loaded multiple times are counted once by their
+// position.
+func Files() []*File {
+	type position struct {
+		file   string
+		offset int
+	}
+	hits := map[position]int{}
+	lines := map[position]int{}
+	for n, count := range consts.COVERAGE {
+		span := n.GetSpan()
+		p := position{span.File, span.Start.Offset}
+		hits[p] += count
+		lines[p] = span.Start.Line
+	}
+
+	files := map[string]*File{}
+	for p, count := range hits {
+		f, ok := files[p.file]
+		if !ok {
+			f = &File{Name: p.file, Lines: map[int]*Line{}}
+			files[p.file] = f
+		}
+		f.Statements++
+		l, ok := f.Lines[lines[p]]
+		if !ok {
+			l = &Line{}
+			f.Lines[lines[p]] = l
+		}
+		if count == 0 {
+			l.Missed = true
+			continue
+		}
+		f.Covered++
+		l.Hits = max(l.Hits, count)
+	}
+	res := make([]*File, 0, len(files))
+	for _, f := range files {
+		res = append(res, f)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Name < res[j].Name
+	})
+	return res
+}
+
+// writes the amount and percentage of covered statements per file and in
+// total, the total is reported as no statements if no file contains any
+func Summary(w io.Writer, files []*File) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "file\tstatements\tcovered")
+	total := &File{}
+	for _, f := range files {
+		fmt.Fprintf(tw, "%s\t%d/%d\t%.1f%%\n", f.Name, f.Covered, f.Statements, f.Percent())
+		total.Statements += f.Statements
+		total.Covered += f.Covered
+	}
+	if total.Statements == 0 {
+		fmt.Fprintln(tw, "total\t0/0\tno statements")
+	} else {
+		fmt.Fprintf(tw, "total\t%d/%d\t%.1f%%\n", total.Covered, total.Statements, total.Percent())
+	}
+	tw.Flush()
+}
+
+// writes the source of each file, every line prefixed with its number and
+// the evaluations of its statements. Lines not covered are marked with !,
+// partially covered lines with ~.
+func Listing(w io.Writer, files []*File) error {
+	for i, f := range files {
+		src, err := os.ReadFile(f.Name)
+		if err != nil {
+			return err
+		}
+		if i != 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s: %.1f%% of statements covered\n", f.Name, f.Percent())
+		for num, text := range strings.Split(strings.TrimSuffix(string(src), "\n"), "\n") {
+			marker, hits := ' ', ""
+			if l, ok := f.Lines[num]; ok {
+				hits = fmt.Sprintf("%dx", l.Hits)
+				if l.Hits == 0 {
+					marker = '!'
+				} else if l.Missed {
+					marker = '~'
+				}
+			}
+			fmt.Fprintf(w, "%5d %c %6s | %s\n", num+1, marker, hits, text)
+		}
+	}
+	return nil
+}
+
+// writes the line coverage of files in the lcov tracefile format, see
+// https://github.com/linux-test-project/lcov
+func Lcov(w io.Writer, files []*File) error {
+	b := bufio.NewWriter(w)
+	for _, f := range files {
+		path, err := filepath.Abs(f.Name)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(b, "TN:\nSF:%s\n", path)
+		nums := make([]int, 0, len(f.Lines))
+		for num := range f.Lines {
+			nums = append(nums, num)
+		}
+		sort.Ints(nums)
+		hit := 0
+		for _, num := range nums {
+			fmt.Fprintf(b, "DA:%d,%d\n", num+1, f.Lines[num].Hits)
+			if f.Lines[num].Hits != 0 {
+				hit++
+			}
+		}
+		fmt.Fprintf(b, "LF:%d\nLH:%d\nend_of_record\n", len(nums), hit)
+	}
+	return b.Flush()
+}
@@ -0,0 +1,113 @@
+package cover
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	_ "github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/parser"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+func TestCoverage(t *testing.T) {
+	src := `(fun sign [n]
+    (if (< n 0) "negative")
+    (if (= n 0) (return "zero"))
+    "positive")
+(fun never [] (println "never"))
+(sign 1)
+(sign 2)
+`
+	file := filepath.Join(t.TempDir(), "sign.phia")
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, file, nil))
+	ast := parser.New(lexer.New(strings.NewReader(src), file).Lex(), file).Parse()
+	if serror.HasErrors() {
+		t.Fatal("unexpected errors")
+	}
+
+	clear(consts.COVERAGE)
+	consts.COVER = true
+	defer func() { consts.COVER = false }()
+	Register(ast)
+	for _, n := range ast {
+		consts.COVERAGE[n]++
+		n.Eval()
+	}
+
+	files := Files()
+	if len(files) != 1 {
+		t.Fatalf("wanted coverage of 1 file, got %d", len(files))
+	}
+	f := files[0]
+	if f.Statements != 10 || f.Covered != 7 {
+		t.Errorf("wanted 7 of 10 statements covered, got %d of %d", f.Covered, f.Statements)
+	}
+	lines := []struct {
+		line   int
+		hits   int
+		missed bool
+	}{
+		{line: 0, hits: 1},
+		{line: 1, hits: 2, missed: true},
+		{line: 2, hits: 2, missed: true},
+		{line: 3, hits: 2},
+		{line: 4, hits: 1, missed: true},
+		{line: 5, hits: 1},
+	}
+	for _, l := range lines {
+		got, ok := f.Lines[l.line]
+		if !ok || got.Hits != l.hits || got.Missed != l.missed {
+			t.Errorf("wanted line %d with %d hits and missed=%t, got %+v", l.line+1, l.hits, l.missed, got)
+		}
+	}
+
+	b := &strings.Builder{}
+	if err := Listing(b, files); err != nil {
+		t.Fatal(err)
+	}
+	for _, exp := range []string{"70.0% of statements covered", "    3 ~     2x |     (if (= n 0) (return \"zero\"))", "    7       1x | (sign 2)"} {
+		if !strings.Contains(b.String(), exp) {
+			t.Errorf("wanted %q in listing, got:\n%s", exp, b.String())
+		}
+	}
+	b.Reset()
+	Lcov(b, files)
+	for _, exp := range []string{"SF:" + file, "DA:3,2", "LF:7\nLH:7\nend_of_record"} {
+		if !strings.Contains(b.String(), exp) {
+			t.Errorf("wanted %q in lcov, got:\n%s", exp, b.String())
+		}
+	}
+}
+
+func TestCoverageTestBlocks(t *testing.T) {
+	src := `(fun square [n] (* n n))
+(test "square" (assert (= (square 2) 4)))
+`
+	file := "square_test.phia"
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, file, nil))
+	ast := parser.New(lexer.New(strings.NewReader(src), file).Lex(), file).Parse()
+	if serror.HasErrors() {
+		t.Fatal("unexpected errors")
+	}
+
+	clear(consts.COVERAGE)
+	Register(ast)
+	files := Files()
+	if len(files) != 1 || files[0].Name != file || files[0].Statements != 2 {
+		t.Fatalf("wanted the function of the test file without the test block, got %+v", files)
+	}
+
+	b := &strings.Builder{}
+	Summary(b, nil)
+	if !strings.Contains(b.String(), "total  0/0         no statements") {
+		t.Errorf("wanted no statements in the total, got:\n%s", b.String())
+	}
+}
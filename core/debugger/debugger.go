@@ -0,0 +1,354 @@
+// debugger pauses the evaluation of sophia programs before statements at
+// breakpoints, after steps and at calls of the breakpoint built-in. While
+// paused, commands read from the input inspect the variables and the call
+// stack, set breakpoints and resume the evaluation, see sophia debug.
+package debugger
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xnacly/sophia/core/alloc"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/shared"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+const PROMPT = "(debug) "
+
+// the debugger stops the evaluation by panicking with ErrQuit if quit is
+// entered or the input ends, recovered by run.Run
+var ErrQuit = errors.New("debugging stopped")
+
+// how the evaluation continues after resuming
+type mode int
+
+const (
+	// pause at breakpoints only
+	CONTINUE mode = iota
+	// pause before the next statement, stepping into functions
+	STEP
+	// pause before the next statement of the current or an outer function
+	NEXT
+	// pause before the next statement of an outer function
+	OUT
+)
+
+// position of a breakpoint, file is an absolute path
+type location struct {
+	file string
+	line int
+}
+
+// implements consts.Debugger
+type Debugger struct {
+	in  *bufio.Scanner
+	out io.Writer
+	// file the breakpoints without a file refer to
+	file        string
+	breakpoints map[location]bool
+	mode        mode
+	// depth of the call stack when resuming, used by NEXT and OUT
+	depth int
+	// location and offset of the last statement, statements following it on
+	// the same line, such as the body of a single line if, do not pause at a
+	// breakpoint again
+	last       location
+	lastOffset int
+	// lines of the files listed, keyed by their name
+	sources map[string][]string
+	// absolute paths of the files evaluated, keyed by their name
+	paths map[string]string
+}
+
+// creates a debugger for file reading commands from in, pausing before the
+// first statement
+func New(file string, in io.Reader, out io.Writer) *Debugger {
+	return &Debugger{
+		in:          bufio.NewScanner(in),
+		out:         out,
+		file:        file,
+		breakpoints: map[location]bool{},
+		mode:        STEP,
+		sources:     map[string][]string{},
+		paths:       map[string]string{},
+	}
+}
+
+// pauses before n if stepping requires it or a breakpoint is set at its line
+func (d *Debugger) Statement(n types.Node) {
+	span := n.GetSpan()
+	file := d.fileOf(span)
+	path, ok := d.paths[file]
+	if !ok {
+		path = abs(file)
+		d.paths[file] = path
+	}
+	loc := location{path, span.Start.Line + 1}
+	depth := len(consts.CALL_STACK)
+	pause := false
+	switch d.mode {
+	case STEP:
+		pause = true
+	case NEXT:
+		pause = depth <= d.depth
+	case OUT:
+		pause = depth < d.depth
+	}
+	reason := "step"
+	following := loc == d.last && span.Start.Offset > d.lastOffset
+	if !pause && d.breakpoints[loc] && !following {
+		pause, reason = true, "breakpoint"
+	}
+	d.last, d.lastOffset = loc, span.Start.Offset
+	if pause {
+		d.pause(span, reason)
+	}
+}
+
+// pauses at the call of the breakpoint built-in
+func (d *Debugger) Breakpoint(tok *token.Token) {
+	d.pause(tok.Span(), "(breakpoint)")
+}
+
+// displays the current position and executes commands until the evaluation
+// is resumed
+func (d *Debugger) pause(span token.Span, reason string) {
+	fmt.Fprintf(d.out, "paused at %s:%d:%d, %s\n", d.fileOf(span), span.Start.Line+1, span.Start.Column+1, reason)
+	d.list(span, 1)
+	for {
+		fmt.Fprint(d.out, PROMPT)
+		if !d.in.Scan() {
+			fmt.Fprintln(d.out)
+			panic(ErrQuit)
+		}
+		if d.command(span, strings.TrimSpace(d.in.Text())) {
+			d.depth = len(consts.CALL_STACK)
+			return
+		}
+	}
+}
+
+// executes a command while paused at span, reports whether the evaluation
+// is resumed
+func (d *Debugger) command(span token.Span, line string) bool {
+	name, arg, _ := strings.Cut(line, " ")
+	arg = strings.TrimSpace(arg)
+	switch name {
+	case "c", "continue":
+		d.mode = CONTINUE
+		return true
+	case "s", "step":
+		d.mode = STEP
+		return true
+	case "n", "next":
+		d.mode = NEXT
+		return true
+	case "o", "out":
+		d.mode = OUT
+		return true
+	case "b", "break":
+		loc, err := d.location(arg)
+		if err != nil {
+			fmt.Fprintln(d.out, err)
+			break
+		}
+		d.breakpoints[loc] = true
+		fmt.Fprintf(d.out, "breakpoint set at %s:%d\n", loc.file, loc.line)
+	case "d", "delete":
+		loc, err := d.location(arg)
+		if err != nil {
+			fmt.Fprintln(d.out, err)
+			break
+		}
+		if !d.breakpoints[loc] {
+			fmt.Fprintf(d.out, "no breakpoint at %s:%d\n", loc.file, loc.line)
+			break
+		}
+		delete(d.breakpoints, loc)
+	case "breakpoints":
+		d.listBreakpoints()
+	case "v", "vars":
+		d.variables()
+	case "p", "print":
+		d.print(arg)
+	case "bt", "stack":
+		d.stack(span)
+	case "l", "list":
+		d.list(span, 5)
+	case "q", "quit":
+		panic(ErrQuit)
+	case "h", "help":
+		fmt.Fprint(d.out, HELP)
+	case "":
+	default:
+		fmt.Fprintf(d.out, "unknown command %q, see help\n", name)
+	}
+	return false
+}
+
+const HELP = `c, continue             resume until the next breakpoint
+s, step                 pause before the next statement, stepping into functions
+n, next                 pause before the next statement of the current function
+o, out                  pause before the next statement after the current function returns
+b, break [file:]line    set a breakpoint
+d, delete [file:]line   remove a breakpoint
+breakpoints             list all breakpoints
+v, vars                 display the parameters of the current function and all variables
+p, print name           display the value of a variable
+bt, stack               display the call stack
+l, list                 display the source around the current statement
+q, quit                 stop the evaluation
+`
+
+// parses [file:]line, the file defaults to the file debugged
+func (d *Debugger) location(arg string) (location, error) {
+	file, line := d.file, arg
+	if i := strings.LastIndex(arg, ":"); i != -1 {
+		file, line = arg[:i], arg[i+1:]
+	}
+	n, err := strconv.Atoi(line)
+	if err != nil || n < 1 {
+		return location{}, fmt.Errorf("expected a breakpoint of the form [file:]line, got %q", arg)
+	}
+	return location{abs(file), n}, nil
+}
+
+func (d *Debugger) listBreakpoints() {
+	locs := make([]location, 0, len(d.breakpoints))
+	for loc := range d.breakpoints {
+		locs = append(locs, loc)
+	}
+	sort.Slice(locs, func(i, j int) bool {
+		if locs[i].file == locs[j].file {
+			return locs[i].line < locs[j].line
+		}
+		return locs[i].file < locs[j].file
+	})
+	for _, loc := range locs {
+		fmt.Fprintf(d.out, "%s:%d\n", loc.file, loc.line)
+	}
+}
+
+// returns the parameters of the innermost function, empty at the top level
+func params() []*expr.Ident {
+	res := make([]*expr.Ident, 0)
+	for i := len(consts.CALL_STACK) - 1; i >= 0; i-- {
+		f := consts.CALL_STACK[i]
+		if f.Builtin {
+			continue
+		}
+		for _, p := range f.Params {
+			if ident, ok := p.(*expr.Ident); ok {
+				res = append(res, ident)
+			}
+		}
+		break
+	}
+	return res
+}
+
+// writes the parameters of the innermost function and all other variables
+// with their values
+func (d *Debugger) variables() {
+	tw := tabwriter.NewWriter(d.out, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "name\tvalue\tscope")
+	isParam := map[string]bool{}
+	for _, p := range params() {
+		isParam[p.Name] = true
+		fmt.Fprintf(tw, "%s\t%s\tparameter\n", p.Name, shared.Repr(consts.SYMBOL_TABLE[p.Key]))
+	}
+	names := make([]string, 0, len(alloc.Default.Variables))
+	for name, key := range alloc.Default.Variables {
+		if _, ok := consts.SYMBOL_TABLE[key]; ok && !isParam[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%s\tglobal\n", name, shared.Repr(consts.SYMBOL_TABLE[alloc.Default.Variables[name]]))
+	}
+	tw.Flush()
+}
+
+// writes the value of the variable called name, parameters of the innermost
+// function shadow other variables
+func (d *Debugger) print(name string) {
+	for _, p := range params() {
+		if p.Name == name {
+			fmt.Fprintln(d.out, shared.Repr(consts.SYMBOL_TABLE[p.Key]))
+			return
+		}
+	}
+	if v, ok := consts.SYMBOL_TABLE[alloc.Default.Variables[name]]; ok {
+		fmt.Fprintln(d.out, shared.Repr(v))
+		return
+	}
+	fmt.Fprintf(d.out, "no variable named %q\n", name)
+}
+
+// writes the frames of the call stack, the innermost first, each with the
+// position it is paused at
+func (d *Debugger) stack(span token.Span) {
+	for i := len(consts.CALL_STACK) - 1; i >= 0; i-- {
+		f := consts.CALL_STACK[i]
+		d.frame(len(consts.CALL_STACK)-1-i, f.Name, f.Builtin, span)
+		span = f.Call.Span()
+	}
+	d.frame(len(consts.CALL_STACK), serror.TOP_LEVEL, false, span)
+}
+
+func (d *Debugger) frame(i int, name string, builtin bool, span token.Span) {
+	if builtin {
+		name += " (built-in)"
+	}
+	fmt.Fprintf(d.out, "#%d %s at %s:%d:%d\n", i, name, d.fileOf(span), span.Start.Line+1, span.Start.Column+1)
+}
+
+// writes the lines around span, n before and after it, marking its line
+func (d *Debugger) list(span token.Span, n int) {
+	file := d.fileOf(span)
+	lines, ok := d.sources[file]
+	if !ok {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return
+		}
+		lines = strings.Split(string(src), "\n")
+		d.sources[file] = lines
+	}
+	line := span.Start.Line
+	for i := max(line-n, 0); i <= line+n && i < len(lines); i++ {
+		marker := "  "
+		if i == line {
+			marker = "->"
+		}
+		fmt.Fprintf(d.out, "%s %4d| %s\n", marker, i+1, lines[i])
+	}
+}
+
+// returns the file of span, the file debugged if unknown
+func (d *Debugger) fileOf(span token.Span) string {
+	if span.File == "" {
+		return d.file
+	}
+	return span.File
+}
+
+func abs(file string) string {
+	if p, err := filepath.Abs(file); err == nil {
+		return p
+	}
+	return file
+}
@@ -0,0 +1,109 @@
+package debugger
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/alloc"
+	_ "github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/parser"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+// evaluates src paused by a debugger reading commands, returns its output
+func debug(t *testing.T, src string, commands ...string) (out string, file string) {
+	file = filepath.Join(t.TempDir(), "debug.phia")
+	if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+		t.Fatal(err)
+	}
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, file, nil))
+	ast := parser.New(lexer.New(strings.NewReader(src), file).Lex(), file).Parse()
+	if serror.HasErrors() {
+		t.Fatal("unexpected errors")
+	}
+	clear(consts.SYMBOL_TABLE)
+	b := &strings.Builder{}
+	consts.DEBUGGER = New(file, strings.NewReader(strings.Join(commands, "\n")), b)
+	defer func() { consts.DEBUGGER = nil }()
+	func() {
+		defer func() {
+			if r := recover(); r != nil && r != ErrQuit {
+				panic(r)
+			}
+		}()
+		for _, n := range ast {
+			expr.EvalStatement(n)
+		}
+	}()
+	return b.String(), file
+}
+
+func TestDebugger(t *testing.T) {
+	src := `(fun square [n]
+    (let r (* n n))
+    r)
+(let total 0)
+(for [i] 3
+    (let total (+ total (square i))))
+(breakpoint)
+(let done true)
+`
+	tests := []struct {
+		name     string
+		commands []string
+		exp      []string
+	}{
+		{
+			name:     "breakpoint",
+			commands: []string{"b 2", "c", "p n", "c", "p n", "d 2", "c", "c"},
+			exp: []string{
+				"paused at %s:1:1, step",
+				"paused at %s:2:5, breakpoint\n      1| (fun square [n]\n->    2|     (let r (* n n))",
+				"(debug) 0\n", "(debug) 1\n",
+				"paused at %s:7:2, (breakpoint)",
+			},
+		},
+		{
+			name:     "stepping",
+			commands: []string{"b 2", "c", "bt", "o", "n", "s", "vars", "c", "c"},
+			exp: []string{
+				"#0 square at %s:2:5\n#1 <top level> at %s:6:26",
+				"(debug) paused at %s:6:5, step",
+				"(debug) paused at %s:2:5, breakpoint",
+				"(debug) paused at %s:3:5, step",
+				"name   value  scope\nn      1      parameter\ni      1      global\nr      1      global\ntotal  0      global\n",
+			},
+		},
+		{
+			name:     "invalid commands",
+			commands: []string{"b x", "d 3", "unknown", "c"},
+			exp:      []string{`expected a breakpoint of the form [file:]line, got "x"`, "no breakpoint at %s:3", `unknown command "unknown"`},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			out, file := debug(t, src, test.commands...)
+			for _, exp := range test.exp {
+				exp = strings.ReplaceAll(exp, "%s", file)
+				if !strings.Contains(out, exp) {
+					t.Errorf("wanted %q in output, got:\n%s", exp, out)
+				}
+			}
+		})
+	}
+}
+
+func TestDebuggerQuit(t *testing.T) {
+	for _, commands := range [][]string{{"n", "q"}, {"n"}} {
+		out, _ := debug(t, "(let a 1)\n(let b 2)\n(let c 3)", commands...)
+		if _, ok := consts.SYMBOL_TABLE[alloc.Default.Variables["c"]]; ok || strings.Count(out, "paused at") != 2 {
+			t.Errorf("wanted the evaluation to stop after %q, got:\n%s", commands, out)
+		}
+	}
+}
@@ -1,7 +1,8 @@
 package eval
 
 import (
-	"fmt"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/shared"
 	"github.com/xnacly/sophia/core/types"
 )
 
@@ -9,12 +10,12 @@ func Eval(t string, ast []types.Node) []string {
 	if t == "repl" {
 		r := make([]string, len(ast))
 		for i, c := range ast {
-			r[i] = fmt.Sprint(c.Eval())
+			r[i] = shared.Repr(c.Eval())
 		}
 		return r
 	}
 	for _, c := range ast {
-		c.Eval()
+		expr.EvalStatement(c)
 	}
 	return []string{}
 }
@@ -6,6 +6,7 @@ import (
 
 	"github.com/xnacly/sophia/core"
 	_ "github.com/xnacly/sophia/core/builtin" // required for built ins, such as println or len
+	"github.com/xnacly/sophia/core/consts"
 	"github.com/xnacly/sophia/core/lexer"
 	"github.com/xnacly/sophia/core/parser"
 	"github.com/xnacly/sophia/core/serror"
@@ -40,11 +41,15 @@ func TestEvalAritmetic(t *testing.T) {
 			str: "(* (+ 6 (- 13 3)) (* 2 (+ 2 (- 8 2))))",
 			exp: "256",
 		},
+		{
+			str: "(+ 0x1F 0o17 0b1010 1e+1)",
+			exp: "66",
+		},
 	}
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 
@@ -95,7 +100,7 @@ func TestEvalVariables(t *testing.T) {
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -184,7 +189,7 @@ func TestEvalConditional(t *testing.T) {
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -207,7 +212,7 @@ func TestEvalMerge(t *testing.T) {
 	}{
 		{
 			str: `(++ "hello" "world")`,
-			exp: "helloworld",
+			exp: `"helloworld"`,
 		},
 		{
 			str: `(let a 1 2)(++ a 1 2)`,
@@ -215,17 +220,17 @@ func TestEvalMerge(t *testing.T) {
 		},
 		{
 			str: `(++ "hello" 1 2)`,
-			exp: "[hello 1 2]",
+			exp: `["hello" 1 2]`,
 		},
 		{
 			str: `(++ 1 2 "hello")`,
-			exp: "[1 2 hello]",
+			exp: `[1 2 "hello"]`,
 		},
 	}
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -258,13 +263,17 @@ func TestEvalFunction(t *testing.T) {
 		},
 		{
 			str: "(fun print [a] (println a))(let y 12 23 12)(print y)",
-			exp: "<nil>",
+			exp: "nil",
+		},
+		{
+			str: "(let n 5)(fun f [n] (+ n \"x\"))(assert-throws (f 1))(+ n 0)",
+			exp: "5",
 		},
 	}
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -295,7 +304,7 @@ func TestEvalLoop(t *testing.T) {
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -341,7 +350,7 @@ func TestEvalReturn(t *testing.T) {
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -378,7 +387,7 @@ func TestEvalObject(t *testing.T) {
 })
 (let bankName person#["bank"]["institute"]["name"])
             `,
-			exp: "western union",
+			exp: `"western union"`,
 		},
 		{
 			str: `
@@ -401,7 +410,7 @@ func TestEvalObject(t *testing.T) {
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -436,7 +445,7 @@ func TestEvalArray(t *testing.T) {
 	for _, i := range input {
 		t.Run(i.str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
-			l := lexer.New(strings.NewReader(i.str))
+			l := lexer.New(strings.NewReader(i.str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -470,7 +479,7 @@ func TestEvalModules(t *testing.T) {
 	for _, str := range input {
 		t.Run(str, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, str, "test", nil))
-			l := lexer.New(strings.NewReader(str))
+			l := lexer.New(strings.NewReader(str), "test")
 			p := parser.New(l.Lex(), "test")
 			r := Eval("repl", p.Parse())
 			if serror.HasErrors() {
@@ -484,3 +493,221 @@ func TestEvalModules(t *testing.T) {
 	}
 
 }
+
+func TestEvalDecimal(t *testing.T) {
+	input := []struct {
+		str string
+		exp string
+	}{
+		{
+			str: "(+ 0.1d 0.2d)",
+			exp: "0.3d",
+		},
+		{
+			str: "(- 500_912.99d 0.99d)",
+			exp: "500912d",
+		},
+		{
+			str: "(* 3d (/ 1d 3d))",
+			exp: "1d",
+		},
+		{
+			str: "(/ 1d 3d)",
+			exp: "0.33333333333333333333d",
+		},
+		{
+			str: "(+ 1 0.5d 0.25)",
+			exp: "1.75d",
+		},
+		{
+			str: `(* (decimal "19.99") 3)`,
+			exp: "59.97d",
+		},
+		{
+			str: "(= 0.3d (+ 0.1d 0.2))",
+			exp: "true",
+		},
+		{
+			str: "(< 0.1d 0.2)",
+			exp: "true",
+		},
+		{
+			str: "(> 0.1d 0.2d)",
+			exp: "false",
+		},
+		{
+			str: "(not 2.5d)",
+			exp: "-2.5d",
+		},
+		{
+			str: "(% 10d 3)",
+			exp: "1d",
+		},
+		{
+			str: "(% -7.5d 2d 1)",
+			exp: "-0.5d",
+		},
+	}
+	for _, i := range input {
+		t.Run(i.str, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
+			l := lexer.New(strings.NewReader(i.str), "test")
+			p := parser.New(l.Lex(), "test")
+			r := Eval("repl", p.Parse())
+			if serror.HasErrors() {
+				t.Errorf("lexer or parser error for %q", i.str)
+			}
+			if len(r) == 0 {
+				t.Errorf("eval result empty for %q", i.str)
+				return
+			}
+			got := r[len(r)-1]
+			if i.exp != got {
+				t.Errorf("got %q, wanted %q", got, i.exp)
+			}
+		})
+	}
+}
+
+func TestEvalDecimalNonFinite(t *testing.T) {
+	input := []string{
+		"(+ (/ 1 0) 1d)",
+		"(* 2d (/ 0 0))",
+		"(< 1d (/ -1 0))",
+		"(= 1d (/ 1 0))",
+		"(decimal (/ 1 0))",
+	}
+	for _, i := range input {
+		t.Run(i, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, i, "test", nil))
+			l := lexer.New(strings.NewReader(i), "test")
+			p := parser.New(l.Lex(), "test")
+			ast := p.Parse()
+			if serror.HasErrors() {
+				t.Fatalf("lexer or parser error for %q", i)
+			}
+			defer func() {
+				err, ok := recover().(*serror.Error)
+				if !ok {
+					t.Fatalf("expected a runtime error")
+				}
+				if err.Title != "Type error" {
+					t.Errorf("got %q, wanted a type error", err.Title)
+				}
+			}()
+			Eval("test", ast)
+		})
+	}
+}
+
+func TestEvalTemplateString(t *testing.T) {
+	input := []struct {
+		str string
+		exp string
+	}{
+		{
+			str: `(let a 5)(let r '{a} items')`,
+			exp: `"5 items"`,
+		},
+		{
+			str: `(let a 5)(let r '{(+ a 1)} items')`,
+			exp: `"6 items"`,
+		},
+		{
+			str: `(let person {name: "anon"})(let r 'Hi {person#["name"]}')`,
+			exp: `"Hi anon"`,
+		},
+		{
+			str: `(let r '{{escaped}}')`,
+			exp: `"{escaped}"`,
+		},
+		{
+			str: `(let price 19.999)(let r '{price:.2f}€')`,
+			exp: `"20.00€"`,
+		},
+		{
+			str: `(let r '[{"anon":>6}] [{"anon":<6}] [{"anon":^6}]')`,
+			exp: `"[  anon] [anon  ] [ anon ]"`,
+		},
+		{
+			str: `(let r '{12:05} {-1.5:.3e} {2.5:d}')`,
+			exp: `"00012 -1.500e+00 3"`,
+		},
+		{
+			str: `(let r '{(/ 1d 3d):.4}')`,
+			exp: `"0.3333"`,
+		},
+		{
+			str: "(let r 'multi\nline')",
+			exp: `"multi\nline"`,
+		},
+	}
+	for _, i := range input {
+		t.Run(i.str, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
+			l := lexer.New(strings.NewReader(i.str), "test")
+			p := parser.New(l.Lex(), "test")
+			r := Eval("repl", p.Parse())
+			if serror.HasErrors() {
+				t.Errorf("lexer or parser error for %q", i.str)
+			}
+			if len(r) == 0 {
+				t.Errorf("eval result empty for %q", i.str)
+				return
+			}
+			got := r[len(r)-1]
+			if i.exp != got {
+				t.Errorf("got %q, wanted %q", got, i.exp)
+			}
+		})
+	}
+}
+
+func TestEvalStackTrace(t *testing.T) {
+	input := []struct {
+		str string
+		// frame names, the innermost first
+		exp []string
+	}{
+		{
+			str: "(println undefined)",
+			exp: []string{"println", serror.TOP_LEVEL},
+		},
+		{
+			str: "(fun inner [a] (+ a undefined))\n(fun outer [a] (inner a))\n(outer 1)",
+			exp: []string{"inner", "outer", serror.TOP_LEVEL},
+		},
+		{
+			str: "(fun f [a] (+ a undefined))\n(map (lambda [x] (f x)) [1 2])",
+			exp: []string{"f", "lambda", "map", serror.TOP_LEVEL},
+		},
+	}
+	for _, i := range input {
+		t.Run(i.str, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, i.str, "test", nil))
+			l := lexer.New(strings.NewReader(i.str), "test")
+			p := parser.New(l.Lex(), "test")
+			ast := p.Parse()
+			if serror.HasErrors() {
+				t.Fatalf("lexer or parser error for %q", i.str)
+			}
+			defer func() {
+				err, ok := recover().(*serror.Error)
+				if !ok {
+					t.Fatalf("expected a runtime error")
+				}
+				names := make([]string, len(err.Trace))
+				for j, f := range err.Trace {
+					names[j] = f.Name
+				}
+				if strings.Join(names, " ") != strings.Join(i.exp, " ") {
+					t.Errorf("got frames %q, wanted %q", names, i.exp)
+				}
+				if len(consts.CALL_STACK) != 0 {
+					t.Errorf("expected the call stack to be empty after the error, got %v", consts.CALL_STACK)
+				}
+			}()
+			Eval("test", ast)
+		})
+	}
+}
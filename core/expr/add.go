@@ -1,12 +1,15 @@
 package expr
 
 import (
+	"math/big"
+
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
 )
 
 type Add struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,21 +25,23 @@ func (a *Add) GetToken() *token.Token {
 	return a.Token
 }
 
+func (a *Add) GetSpan() token.Span {
+	return a.Span
+}
+
 func (a *Add) Eval() any {
 	if len(a.Children) == 2 {
 		// fastpath for two children
 		f := a.Children[0]
 		s := a.Children[1]
-		return castFloatPanic(f.Eval(), f.GetToken()) + castFloatPanic(s.Eval(), s.GetToken())
-	}
-
-	res := 0.0
-	for i, c := range a.Children {
-		if i == 0 {
-			res = castFloatPanic(c.Eval(), c.GetToken())
-		} else {
-			res += castFloatPanic(c.Eval(), c.GetToken())
+		fv, sv := f.Eval(), s.Eval()
+		if isDecimal(fv) || isDecimal(sv) {
+			return types.Decimal{Rat: new(big.Rat).Add(castDecimalPanic(fv, f), castDecimalPanic(sv, s))}
 		}
+		return castFloatPanic(fv, f) + castFloatPanic(sv, s)
 	}
-	return res
+
+	return arithmetic(a.Children, func(a, b float64) float64 {
+		return a + b
+	}, (*big.Rat).Add)
 }
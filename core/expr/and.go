@@ -7,6 +7,7 @@ import (
 
 type And struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,16 +23,20 @@ func (a *And) GetToken() *token.Token {
 	return a.Token
 }
 
+func (a *And) GetSpan() token.Span {
+	return a.Span
+}
+
 func (a *And) Eval() any {
 	// fastpaths
 	if len(a.Children) == 2 {
 		f := a.Children[0]
 		s := a.Children[1]
-		return castBoolPanic(f.Eval(), f.GetToken()) && castBoolPanic(s.Eval(), s.GetToken())
+		return castBoolPanic(f.Eval(), f) && castBoolPanic(s.Eval(), s)
 	}
 
 	for _, c := range a.Children {
-		v := castBoolPanic(c.Eval(), a.Token)
+		v := castBoolPanic(c.Eval(), c)
 		if !v {
 			return false
 		}
@@ -20,6 +20,10 @@ func (a *Any) GetToken() *token.Token {
 	return nil
 }
 
+func (a *Any) GetSpan() token.Span {
+	return token.Span{}
+}
+
 func (a *Any) Eval() any {
 	return a.Value
 }
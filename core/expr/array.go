@@ -7,6 +7,7 @@ import (
 
 type Array struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,6 +23,10 @@ func (a *Array) GetToken() *token.Token {
 	return a.Token
 }
 
+func (a *Array) GetSpan() token.Span {
+	return a.Span
+}
+
 func (a *Array) Eval() any {
 	if len(a.Children) == 0 {
 		return []any{}
@@ -20,6 +20,10 @@ func (b *Boolean) GetToken() *token.Token {
 	return b.Token
 }
 
+func (b *Boolean) GetSpan() token.Span {
+	return b.Token.Span()
+}
+
 func (b *Boolean) Eval() any {
 	return b.Value
 }
@@ -1,7 +1,9 @@
 package expr
 
 import (
+	"github.com/xnacly/sophia/core/alloc"
 	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/profile"
 	"github.com/xnacly/sophia/core/serror"
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
@@ -9,6 +11,7 @@ import (
 
 type Call struct {
 	Token *token.Token
+	Span  token.Span
 	Key   uint32
 	Args  []types.Node
 }
@@ -25,10 +28,20 @@ func (c *Call) GetToken() *token.Token {
 	return c.Token
 }
 
+func (c *Call) GetSpan() token.Span {
+	return c.Span
+}
+
 func (c *Call) Eval() any {
 	storedFunc, ok := consts.FUNC_TABLE[c.Key]
 	if !ok {
-		serror.Add(c.Token, "Undefined function", "Function %q not defined", c.Token.Raw)
+		defined := make([]string, 0, len(alloc.Default.Functions))
+		for name, key := range alloc.Default.Functions {
+			if _, ok := consts.FUNC_TABLE[key]; ok {
+				defined = append(defined, name)
+			}
+		}
+		serror.Add(c.Token, "Undefined function", "Function %q not defined", c.Token.Raw).Suggest(c.Token.Raw, defined)
 		serror.Panic()
 	}
 
@@ -38,24 +51,56 @@ func (c *Call) Eval() any {
 		// this branch is hit if a function is not of type *Func which only
 		// happens for built ins, thus the cast can not fail
 		function, _ := storedFunc.(types.KnownFunctionInterface)
+		pushFrame(consts.Frame{Name: c.Token.Raw, Call: c.Token, Builtin: true})
+		defer popFrame()
 		return function(c.Token, c.Args...)
 	}
 
 	return callFunction(c.Token, def.Body, def.Params, c.Args)
 }
 
+// adds the frame of a called function to the call stack
+func pushFrame(f consts.Frame) {
+	consts.CALL_STACK = append(consts.CALL_STACK, f)
+	if consts.PROFILE {
+		profile.EnterFunction(f.Name)
+	}
+}
+
+// removes the innermost frame from the call stack once a function returns,
+// deferred to keep the stack consistent for recovered runtime errors
+func popFrame() {
+	consts.CALL_STACK = consts.CALL_STACK[:len(consts.CALL_STACK)-1]
+	if consts.PROFILE {
+		profile.LeaveFunction()
+	}
+}
+
 func callFunction(tok *token.Token, body []types.Node, params *Array, args []types.Node) any {
 	if len(params.Children) != len(args) {
 		argLen := len(args)
 		if len(params.Children) < argLen {
-			serror.Add(tok, "Too many arguments", "Too many arguments for %q, wanted %d, got %d", tok.Raw, len(params.Children), len(args))
+			serror.Add(tok, "Too many arguments", "Too many arguments for %q, wanted %d, got %d", tok.Raw, len(params.Children), len(args)).
+				Label(params.Span, "%q defined here", tok.Raw)
 			serror.Panic()
 		} else if len(params.Children) > argLen {
-			serror.Add(tok, "Not enough arguments", "Not enough arguments for %q, wanted %d, got %d", tok.Raw, len(params.Children), len(args))
+			serror.Add(tok, "Not enough arguments", "Not enough arguments for %q, wanted %d, got %d", tok.Raw, len(params.Children), len(args)).
+				Label(params.Span, "%q defined here", tok.Raw)
 			serror.Panic()
 		}
 	}
 
+	defer func() {
+		// going out of scope, therefore we restore variables used in the
+		// function scope to their previous value stored in the local scope
+		// table, deferred before evaluating the parameters and the body to
+		// also restore them for recovered runtime errors
+		for k, v := range consts.SCOPE_TABLE {
+			consts.SYMBOL_TABLE[k] = v
+			delete(consts.SCOPE_TABLE, k)
+		}
+	}()
+
 	// store variable values from before entering the function scope
 	for i, arg := range args {
 		identifier := params.Children[i].(*Ident)
@@ -65,6 +110,10 @@ func callFunction(tok *token.Token, body []types.Node, params *Array, args []typ
 		consts.SYMBOL_TABLE[identifier.Key] = arg.Eval()
 	}
 
+	// arguments are evaluated in the frame of the caller
+	pushFrame(consts.Frame{Name: tok.Raw, Call: tok, Params: params.Children})
+	defer popFrame()
+
 	var ret any
 
 	for i, stmt := range body {
@@ -76,10 +125,10 @@ func callFunction(tok *token.Token, body []types.Node, params *Array, args []typ
 			break
 		}
 		if i+1 == len(body) {
-			ret = stmt.Eval()
+			ret = EvalStatement(stmt)
 			break
 		}
-		stmt.Eval()
+		EvalStatement(stmt)
 	}
 
 	// if last line was a return
@@ -89,15 +138,6 @@ func callFunction(tok *token.Token, body []types.Node, params *Array, args []typ
 		consts.RETURN.Value = nil
 	}
 
-	defer func() {
-		// going out of scope, therefore we restore variables used in the
-		// function scope to their previous value stored in the local scope table
-		for k, v := range consts.SCOPE_TABLE {
-			consts.SYMBOL_TABLE[k] = v
-			delete(consts.SCOPE_TABLE, k)
-		}
-	}()
-
 	return ret
 
 }
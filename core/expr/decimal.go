@@ -0,0 +1,29 @@
+package expr
+
+import (
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+type Decimal struct {
+	Token *token.Token
+	Value types.Decimal
+}
+
+func (d *Decimal) GetChildren() []types.Node {
+	return nil
+}
+
+func (n *Decimal) SetChildren(c []types.Node) {}
+
+func (d *Decimal) GetToken() *token.Token {
+	return d.Token
+}
+
+func (d *Decimal) GetSpan() token.Span {
+	return d.Token.Span()
+}
+
+func (d *Decimal) Eval() any {
+	return d.Value
+}
@@ -1,12 +1,16 @@
 package expr
 
 import (
+	"math/big"
+
+	"github.com/xnacly/sophia/core/serror"
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
 )
 
 type Div struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,20 +26,32 @@ func (d *Div) GetToken() *token.Token {
 	return d.Token
 }
 
+func (d *Div) GetSpan() token.Span {
+	return d.Span
+}
+
+// decimal division, panics instead of returning NaN or Inf for zero divisors
+func (d *Div) quo(z, a, b *big.Rat) *big.Rat {
+	if b.Sign() == 0 {
+		serror.Add(d.Token, "Division by zero", "Can not divide a decimal by zero")
+		serror.Panic()
+	}
+	return z.Quo(a, b)
+}
+
 func (d *Div) Eval() any {
 	if len(d.Children) == 2 {
 		// fastpath for two children
 		f := d.Children[0]
 		s := d.Children[1]
-		return castFloatPanic(f.Eval(), f.GetToken()) / castFloatPanic(s.Eval(), s.GetToken())
-	}
-	res := 0.0
-	for i, c := range d.Children {
-		if i == 0 {
-			res = castFloatPanic(c.Eval(), c.GetToken())
-		} else {
-			res /= castFloatPanic(c.Eval(), c.GetToken())
+		fv, sv := f.Eval(), s.Eval()
+		if isDecimal(fv) || isDecimal(sv) {
+			return types.Decimal{Rat: d.quo(new(big.Rat), castDecimalPanic(fv, f), castDecimalPanic(sv, s))}
 		}
+		return castFloatPanic(fv, f) / castFloatPanic(sv, s)
 	}
-	return res
+
+	return arithmetic(d.Children, func(a, b float64) float64 {
+		return a / b
+	}, d.quo)
 }
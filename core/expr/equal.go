@@ -7,6 +7,7 @@ import (
 
 type Equal struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,15 +23,19 @@ func (e *Equal) GetToken() *token.Token {
 	return e.Token
 }
 
+func (e *Equal) GetSpan() token.Span {
+	return e.Span
+}
+
 func (e *Equal) Eval() any {
 	if len(e.Children) == 2 {
 		// skipping list creating for multiple equal children
-		return e.Children[0].Eval() == e.Children[1].Eval()
+		return Equals(e.Children[0].Eval(), e.Children[1].Eval(), e.Token)
 	}
 	list := make([]any, len(e.Children))
 	for i, c := range e.Children {
 		list[i] = c.Eval()
-		if i >= 1 && list[i-1] != list[i] {
+		if i >= 1 && !Equals(list[i-1], list[i], e.Token) {
 			return false
 		}
 	}
@@ -20,6 +20,10 @@ func (f *Float) GetToken() *token.Token {
 	return f.Token
 }
 
+func (f *Float) GetSpan() token.Span {
+	return f.Token.Span()
+}
+
 func (f *Float) Eval() any {
 	return f.Value
 }
@@ -10,6 +10,7 @@ import (
 // function definition
 type For struct {
 	Token    *token.Token
+	Span     token.Span
 	Params   *Array
 	LoopOver types.Node
 	Body     []types.Node
@@ -27,6 +28,10 @@ func (f *For) GetToken() *token.Token {
 	return f.Token
 }
 
+func (f *For) GetSpan() token.Span {
+	return f.Span
+}
+
 func (f *For) Eval() any {
 	params := f.Params.Children
 	if len(params) < 1 {
@@ -44,7 +49,7 @@ func (f *For) Eval() any {
 		for _, el := range loopOver {
 			consts.SYMBOL_TABLE[element.Key] = el
 			for _, stmt := range f.Body {
-				stmt.Eval()
+				EvalStatement(stmt)
 			}
 		}
 	case float64:
@@ -52,7 +57,7 @@ func (f *For) Eval() any {
 		for i := 0.0; i < con; i++ {
 			consts.SYMBOL_TABLE[element.Key] = i
 			for _, stmt := range f.Body {
-				stmt.Eval()
+				EvalStatement(stmt)
 			}
 		}
 	default:
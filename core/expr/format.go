@@ -0,0 +1,44 @@
+package expr
+
+import (
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/shared"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+// interpolation with a format specifier inside of a template string:
+// '{price:.2f}'
+type Format struct {
+	Token *token.Token
+	Child types.Node
+	Spec  shared.FormatSpec
+}
+
+func (f *Format) GetChildren() []types.Node {
+	return []types.Node{f.Child}
+}
+
+func (f *Format) SetChildren(c []types.Node) {
+	if len(c) == 0 {
+		return
+	}
+	f.Child = c[0]
+}
+
+func (f *Format) GetToken() *token.Token {
+	return f.Token
+}
+
+func (f *Format) GetSpan() token.Span {
+	return f.Child.GetSpan().Join(f.Token.Span())
+}
+
+func (f *Format) Eval() any {
+	s, err := f.Spec.Format(f.Child.Eval())
+	if err != nil {
+		serror.Add(f.Token, "Format error", "Can't apply format specifier %q: %s", f.Token.Raw, err)
+		serror.Panic()
+	}
+	return s
+}
@@ -1,6 +1,8 @@
 package expr
 
 import (
+	"strings"
+
 	"github.com/xnacly/sophia/core/consts"
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
@@ -9,9 +11,11 @@ import (
 // function definition
 type Func struct {
 	Token  *token.Token
+	Span   token.Span
 	Name   types.Node
 	Params *Array
 	Body   []types.Node
+	Doc    string // content of the doc comments preceding the definition
 }
 
 func (f *Func) GetChildren() []types.Node {
@@ -26,8 +30,41 @@ func (f *Func) GetToken() *token.Token {
 	return f.Token
 }
 
+func (f *Func) GetSpan() token.Span {
+	return f.Span
+}
+
 func (f *Func) Eval() any {
 	ident := f.Name.(*Ident)
 	consts.FUNC_TABLE[ident.Key] = f
 	return nil
 }
+
+// formats the definition as written in the source, including its type
+// annotations: (fun square:float [n:float])
+func (f *Func) Signature() string {
+	b := strings.Builder{}
+	b.WriteString("(fun ")
+	if ident, ok := f.Name.(*Ident); ok {
+		b.WriteString(ident.Name)
+		if ident.Type != nil {
+			b.WriteString(":" + ident.Type.Raw)
+		}
+	}
+	b.WriteString(" [")
+	if f.Params != nil {
+		for i, p := range f.Params.Children {
+			if i > 0 {
+				b.WriteRune(' ')
+			}
+			if ident, ok := p.(*Ident); ok {
+				b.WriteString(ident.Name)
+				if ident.Type != nil {
+					b.WriteString(":" + ident.Type.Raw)
+				}
+			}
+		}
+	}
+	b.WriteString("])")
+	return b.String()
+}
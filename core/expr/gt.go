@@ -7,6 +7,7 @@ import (
 
 type Gt struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,6 +23,16 @@ func (g *Gt) GetToken() *token.Token {
 	return g.Token
 }
 
+func (g *Gt) GetSpan() token.Span {
+	return g.Span
+}
+
 func (g *Gt) Eval() any {
-	return castFloatPanic(g.Children[0].Eval(), g.Children[0].GetToken()) > castFloatPanic(g.Children[1].Eval(), g.Children[1].GetToken())
+	f := g.Children[0]
+	s := g.Children[1]
+	fv, sv := f.Eval(), s.Eval()
+	if isDecimal(fv) || isDecimal(sv) {
+		return castDecimalPanic(fv, f).Cmp(castDecimalPanic(sv, s)) > 0
+	}
+	return castFloatPanic(fv, f) > castFloatPanic(sv, s)
 }
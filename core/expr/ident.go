@@ -1,6 +1,7 @@
 package expr
 
 import (
+	"github.com/xnacly/sophia/core/alloc"
 	"github.com/xnacly/sophia/core/consts"
 	"github.com/xnacly/sophia/core/serror"
 	"github.com/xnacly/sophia/core/token"
@@ -12,6 +13,9 @@ type Ident struct {
 	Token *token.Token
 	Key   uint32
 	Name  string
+	// optional type annotation, such as float in (let a:float 1), nil if
+	// not annotated
+	Type *token.Token
 }
 
 func (i *Ident) GetChildren() []types.Node {
@@ -24,10 +28,23 @@ func (i *Ident) GetToken() *token.Token {
 	return i.Token
 }
 
+func (i *Ident) GetSpan() token.Span {
+	if i.Type != nil {
+		return i.Token.Span().Join(i.Type.Span())
+	}
+	return i.Token.Span()
+}
+
 func (i *Ident) Eval() any {
 	val, ok := consts.SYMBOL_TABLE[i.Key]
 	if !ok {
-		serror.Add(i.Token, "Undefined variable", "Variable %q is not defined.", i.Name)
+		defined := make([]string, 0, len(alloc.Default.Variables))
+		for name, key := range alloc.Default.Variables {
+			if _, ok := consts.SYMBOL_TABLE[key]; ok {
+				defined = append(defined, name)
+			}
+		}
+		serror.Add(i.Token, "Undefined variable", "Variable %q is not defined.", i.Name).Suggest(i.Name, defined)
 		serror.Panic()
 	}
 	return val
@@ -7,6 +7,7 @@ import (
 
 type If struct {
 	Token     *token.Token
+	Span      token.Span
 	Condition types.Node
 	Body      []types.Node
 }
@@ -23,13 +24,17 @@ func (i *If) GetToken() *token.Token {
 	return i.Token
 }
 
+func (i *If) GetSpan() token.Span {
+	return i.Span
+}
+
 func (i *If) Eval() any {
-	cond := castBoolPanic(i.Condition.Eval(), i.Condition.GetToken())
+	cond := castBoolPanic(i.Condition.Eval(), i.Condition)
 	if !cond {
 		return false
 	}
 	for _, c := range i.Body {
-		c.Eval()
+		EvalStatement(c)
 	}
 	return true
 }
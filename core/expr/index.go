@@ -9,6 +9,7 @@ import (
 
 type Index struct {
 	Token  *token.Token
+	Span   token.Span
 	Target types.Node
 	Index  []types.Node
 }
@@ -22,6 +23,10 @@ func (i *Index) GetToken() *token.Token {
 	return i.Token
 }
 
+func (i *Index) GetSpan() token.Span {
+	return i.Span
+}
+
 func indexHelper(target any, index []types.Node) any {
 	switch v := target.(type) {
 	case []interface{}:
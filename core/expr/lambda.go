@@ -8,6 +8,7 @@ import (
 
 type Lambda struct {
 	Token  *token.Token
+	Span   token.Span
 	Body   []types.Node
 	Params *Array
 	Args   []types.Node
@@ -25,6 +26,10 @@ func (l *Lambda) GetToken() *token.Token {
 	return l.Token
 }
 
+func (l *Lambda) GetSpan() token.Span {
+	return l.Span
+}
+
 func (l *Lambda) Eval() any {
 	if len(l.Args) == 0 {
 		serror.Add(l.Token, "Illogical lambda", "Lambda got no argument, consider using it with the map or filter built-ins")
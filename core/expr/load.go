@@ -8,6 +8,7 @@ import (
 // parser only structure
 type Load struct {
 	Token   *token.Token
+	Span    token.Span
 	Imports []types.Node
 }
 
@@ -21,6 +22,10 @@ func (l *Load) GetToken() *token.Token {
 	return l.Token
 }
 
+func (l *Load) GetSpan() token.Span {
+	return l.Span
+}
+
 func (l *Load) Eval() any {
 	return nil
 }
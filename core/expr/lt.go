@@ -7,6 +7,7 @@ import (
 
 type Lt struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,6 +23,16 @@ func (l *Lt) GetToken() *token.Token {
 	return l.Token
 }
 
+func (l *Lt) GetSpan() token.Span {
+	return l.Span
+}
+
 func (l *Lt) Eval() any {
-	return castFloatPanic(l.Children[0].Eval(), l.Children[0].GetToken()) < castFloatPanic(l.Children[1].Eval(), l.Children[1].GetToken())
+	f := l.Children[0]
+	s := l.Children[1]
+	fv, sv := f.Eval(), s.Eval()
+	if isDecimal(fv) || isDecimal(sv) {
+		return castDecimalPanic(fv, f).Cmp(castDecimalPanic(sv, s)) < 0
+	}
+	return castFloatPanic(fv, f) < castFloatPanic(sv, s)
 }
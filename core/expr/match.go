@@ -7,6 +7,7 @@ import (
 
 type Match struct {
 	Token    *token.Token
+	Span     token.Span
 	Branches []types.Node
 }
 
@@ -22,6 +23,10 @@ func (m *Match) GetToken() *token.Token {
 	return m.Token
 }
 
+func (m *Match) GetSpan() token.Span {
+	return m.Span
+}
+
 func (m *Match) Eval() any {
 	// fastpath: skip loop and lookup
 	if len(m.Branches) == 0 {
@@ -29,12 +34,12 @@ func (m *Match) Eval() any {
 	}
 	for _, c := range m.Branches {
 		if c.GetToken().Type == token.IF {
-			o := c.Eval()
+			o := EvalStatement(c)
 			if o.(bool) {
 				return nil
 			}
 		} else {
-			return c.Eval()
+			return EvalStatement(c)
 		}
 	}
 	return nil
@@ -8,6 +8,7 @@ import (
 
 type Merge struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -23,6 +24,10 @@ func (m *Merge) GetToken() *token.Token {
 	return m.Token
 }
 
+func (m *Merge) GetSpan() token.Span {
+	return m.Span
+}
+
 func (m *Merge) Eval() any {
 	if len(m.Children) == 1 {
 		return []any{m.Children[0].Eval()}
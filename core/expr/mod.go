@@ -2,12 +2,16 @@ package expr
 
 import (
 	"math"
+	"math/big"
+
+	"github.com/xnacly/sophia/core/serror"
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
 )
 
 type Mod struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -23,21 +27,33 @@ func (m *Mod) GetToken() *token.Token {
 	return m.Token
 }
 
+func (m *Mod) GetSpan() token.Span {
+	return m.Span
+}
+
+// decimal remainder, truncating the quotient like math.Mod does, panics for
+// zero divisors
+func (m *Mod) rem(z, a, b *big.Rat) *big.Rat {
+	if b.Sign() == 0 {
+		serror.Add(m.Token, "Division by zero", "Can not compute the remainder of a decimal divided by zero")
+		serror.Panic()
+	}
+	q := new(big.Rat).Quo(a, b)
+	t := new(big.Rat).SetInt(new(big.Int).Quo(q.Num(), q.Denom()))
+	return z.Sub(a, t.Mul(t, b))
+}
+
 func (m *Mod) Eval() any {
 	if len(m.Children) == 2 {
 		// fastpath for two children
 		f := m.Children[0]
 		s := m.Children[1]
-		return math.Mod(castFloatPanic(f.Eval(), f.GetToken()), castFloatPanic(s.Eval(), s.GetToken()))
-	}
-
-	res := 0.0
-	for i, c := range m.Children {
-		if i == 0 {
-			res = castFloatPanic(c.Eval(), c.GetToken())
-		} else {
-			res = math.Mod(res, castFloatPanic(c.Eval(), c.GetToken()))
+		fv, sv := f.Eval(), s.Eval()
+		if isDecimal(fv) || isDecimal(sv) {
+			return types.Decimal{Rat: m.rem(new(big.Rat), castDecimalPanic(fv, f), castDecimalPanic(sv, s))}
 		}
+		return math.Mod(castFloatPanic(fv, f), castFloatPanic(sv, s))
 	}
-	return float64(res)
+
+	return arithmetic(m.Children, math.Mod, m.rem)
 }
@@ -8,8 +8,10 @@ import (
 
 type Module struct {
 	Token    *token.Token
+	Span     token.Span
 	Name     string
 	Children []types.Node
+	Doc      string // content of the doc comments preceding the definition
 }
 
 func (m *Module) GetChildren() []types.Node {
@@ -24,6 +26,10 @@ func (m *Module) GetToken() *token.Token {
 	return m.Token
 }
 
+func (m *Module) GetSpan() token.Span {
+	return m.Span
+}
+
 func (m *Module) Eval() any {
 	consts.MODULE_TABLE[m.Name] = m
 	return nil
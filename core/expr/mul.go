@@ -1,12 +1,15 @@
 package expr
 
 import (
+	"math/big"
+
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
 )
 
 type Mul struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,21 +25,23 @@ func (m *Mul) GetToken() *token.Token {
 	return m.Token
 }
 
+func (m *Mul) GetSpan() token.Span {
+	return m.Span
+}
+
 func (m *Mul) Eval() any {
 	if len(m.Children) == 2 {
 		// fastpath for two children
 		f := m.Children[0]
 		s := m.Children[1]
-		return castFloatPanic(f.Eval(), f.GetToken()) * castFloatPanic(s.Eval(), s.GetToken())
-	}
-
-	res := 0.0
-	for i, c := range m.Children {
-		if i == 0 {
-			res = castFloatPanic(c.Eval(), c.GetToken())
-		} else {
-			res *= castFloatPanic(c.Eval(), c.GetToken())
+		fv, sv := f.Eval(), s.Eval()
+		if isDecimal(fv) || isDecimal(sv) {
+			return types.Decimal{Rat: new(big.Rat).Mul(castDecimalPanic(fv, f), castDecimalPanic(sv, s))}
 		}
+		return castFloatPanic(fv, f) * castFloatPanic(sv, s)
 	}
-	return res
+
+	return arithmetic(m.Children, func(a, b float64) float64 {
+		return a * b
+	}, (*big.Rat).Mul)
 }
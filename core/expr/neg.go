@@ -1,6 +1,8 @@
 package expr
 
 import (
+	"math/big"
+
 	"github.com/xnacly/sophia/core/serror"
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
@@ -8,6 +10,7 @@ import (
 
 type Neg struct {
 	Token    *token.Token
+	Span     token.Span
 	Children types.Node
 }
 
@@ -26,6 +29,10 @@ func (n *Neg) GetToken() *token.Token {
 	return n.Token
 }
 
+func (n *Neg) GetSpan() token.Span {
+	return n.Span
+}
+
 func (n *Neg) Eval() any {
 	child := n.Children.Eval()
 	switch v := child.(type) {
@@ -33,11 +40,13 @@ func (n *Neg) Eval() any {
 		return false
 	case float64:
 		return v * -1
+	case types.Decimal:
+		return types.Decimal{Rat: new(big.Rat).Neg(v.Rat)}
 	case bool:
 		return !v
 	default:
 		t := n.Children.GetToken()
-		serror.Add(t, "Type Error", "Expected float64, bool or nil, got %T", child)
+		serror.Add(t, "Type error", "Expected float64, decimal, bool or nil, got %T", child)
 		serror.Panic()
 	}
 	return nil
@@ -13,6 +13,7 @@ type ObjectPair struct {
 
 type Object struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []ObjectPair
 }
 
@@ -26,6 +27,10 @@ func (o *Object) GetToken() *token.Token {
 	return o.Token
 }
 
+func (o *Object) GetSpan() token.Span {
+	return o.Span
+}
+
 func (o *Object) Eval() any {
 	m := make(map[string]any, len(o.Children))
 	for _, c := range o.Children {
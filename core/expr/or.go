@@ -7,6 +7,7 @@ import (
 
 type Or struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,14 +23,18 @@ func (o *Or) GetToken() *token.Token {
 	return o.Token
 }
 
+func (o *Or) GetSpan() token.Span {
+	return o.Span
+}
+
 func (o *Or) Eval() any {
 	if len(o.Children) == 2 {
 		f := o.Children[0]
 		s := o.Children[1]
-		return castBoolPanic(f.Eval(), f.GetToken()) || castBoolPanic(s.Eval(), s.GetToken())
+		return castBoolPanic(f.Eval(), f) || castBoolPanic(s.Eval(), s)
 	}
 	for _, c := range o.Children {
-		if castBoolPanic(c.Eval(), c.GetToken()) {
+		if castBoolPanic(c.Eval(), c) {
 			return true
 		}
 	}
@@ -8,6 +8,7 @@ import (
 
 type Return struct {
 	Token *token.Token
+	Span  token.Span
 	Child types.Node
 }
 
@@ -23,6 +24,10 @@ func (r *Return) GetToken() *token.Token {
 	return r.Token
 }
 
+func (r *Return) GetSpan() token.Span {
+	return r.Span
+}
+
 func (r *Return) Eval() any {
 	if r.Child == nil {
 		return nil
@@ -21,6 +21,10 @@ func (r *Root) GetToken() *token.Token {
 	return nil
 }
 
+func (r *Root) GetSpan() token.Span {
+	return token.Span{}
+}
+
 func (r *Root) Eval() any {
 	return nil
 }
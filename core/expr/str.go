@@ -19,6 +19,10 @@ func (s *String) GetToken() *token.Token {
 	return s.Token
 }
 
+func (s *String) GetSpan() token.Span {
+	return s.Token.Span()
+}
+
 func (s *String) Eval() any {
 	return s.Token.Raw
 }
@@ -1,12 +1,15 @@
 package expr
 
 import (
+	"math/big"
+
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
 )
 
 type Sub struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -22,21 +25,23 @@ func (s *Sub) GetToken() *token.Token {
 	return s.Token
 }
 
+func (s *Sub) GetSpan() token.Span {
+	return s.Span
+}
+
 func (s *Sub) Eval() any {
 	if len(s.Children) == 2 {
 		// fastpath for two children
 		f := s.Children[0]
 		s := s.Children[1]
-		return castFloatPanic(f.Eval(), f.GetToken()) - castFloatPanic(s.Eval(), s.GetToken())
-	}
-
-	res := 0.0
-	for i, c := range s.Children {
-		if i == 0 {
-			res = castFloatPanic(c.Eval(), c.GetToken())
-		} else {
-			res -= castFloatPanic(c.Eval(), c.GetToken())
+		fv, sv := f.Eval(), s.Eval()
+		if isDecimal(fv) || isDecimal(sv) {
+			return types.Decimal{Rat: new(big.Rat).Sub(castDecimalPanic(fv, f), castDecimalPanic(sv, s))}
 		}
+		return castFloatPanic(fv, f) - castFloatPanic(sv, s)
 	}
-	return res
+
+	return arithmetic(s.Children, func(a, b float64) float64 {
+		return a - b
+	}, (*big.Rat).Sub)
 }
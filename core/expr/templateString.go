@@ -7,10 +7,9 @@ import (
 	"strings"
 )
 
-var buffer = &strings.Builder{}
-
 type TemplateString struct {
 	Token    *token.Token
+	Span     token.Span
 	Children []types.Node
 }
 
@@ -26,12 +25,18 @@ func (s *TemplateString) GetToken() *token.Token {
 	return s.Token
 }
 
+func (s *TemplateString) GetSpan() token.Span {
+	return s.Span
+}
+
 func (s *TemplateString) Eval() any {
 	if len(s.Children) == 0 {
 		return ""
 	}
 
-	buffer.Reset()
+	// interpolated expressions may evaluate template strings themselves,
+	// therefore a shared buffer can not be used
+	buffer := &strings.Builder{}
 	shared.FormatHelper(buffer, s.Children, 0)
 	return buffer.String()
 }
@@ -9,6 +9,7 @@ import (
 
 type Use struct {
 	Token *token.Token
+	Span  token.Span
 	Name  types.Node
 }
 
@@ -22,18 +23,22 @@ func (u *Use) GetToken() *token.Token {
 	return u.Token
 }
 
+func (u *Use) GetSpan() token.Span {
+	return u.Span
+}
+
 func (u *Use) Eval() any {
 	ident, _ := u.Name.(*Ident)
 	module, ok := consts.MODULE_TABLE[ident.Name]
 	if !ok {
-		serror.Add(ident.Token, "Undefined Module", "Can't find a module named %q", ident.Name)
+		serror.Add(ident.Token, "Undefined module", "Can't find a module named %q", ident.Name)
 		serror.Panic()
 	}
 	m := module.(*Module)
 	for _, c := range m.Children {
 		function, ok := c.(*Func)
 		if !ok {
-			serror.Add(c.GetToken(), "Type Error", "Expected a function inside a module, got %T", c)
+			serror.Add(c.GetToken(), "Type error", "Expected a function inside a module, got %T", c)
 			serror.Panic()
 		}
 		fName := function.Name.(*Ident)
@@ -1,17 +1,23 @@
 package expr
 
 import (
+	"fmt"
+	"math/big"
+
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/profile"
 	"github.com/xnacly/sophia/core/serror"
 	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
 )
 
 // fastpath for casting bool, reduces memory allocation by skipping allocation
-func castBoolPanic(in any, t *token.Token) bool {
+func castBoolPanic(in any, n types.Node) bool {
 	switch v := in.(type) {
 	case bool:
 		return v
 	default:
-		serror.Add(t, "Type error", "Expected value of type bool, got %s", token.TOKEN_NAME_MAP[t.Type])
+		serror.AddNode(n, "Type error", "Expected value of type bool, got %s", token.TOKEN_NAME_MAP[n.GetToken().Type])
 		serror.Panic()
 	}
 	// technically unreachable
@@ -19,12 +25,12 @@ func castBoolPanic(in any, t *token.Token) bool {
 }
 
 // fastpath for casting float64, reduces memory allocation by skipping allocation
-func castFloatPanic(in any, t *token.Token) float64 {
+func castFloatPanic(in any, n types.Node) float64 {
 	switch v := in.(type) {
 	case float64:
 		return v
 	default:
-		serror.Add(t, "Type error", "Expected value of type float, got %s", token.TOKEN_NAME_MAP[t.Type])
+		serror.AddNode(n, "Type error", "Expected value of type float, got %s", token.TOKEN_NAME_MAP[n.GetToken().Type])
 		serror.Panic()
 	}
 	// technically unreachable
@@ -42,3 +48,120 @@ func castPanicIfNotType[T any](in any, t *token.Token) T {
 	}
 	return val
 }
+
+// reports whether in is an arbitrary precision decimal
+func isDecimal(in any) bool {
+	_, ok := in.(types.Decimal)
+	return ok
+}
+
+// converts in to a rational number, promotes finite float64 values to
+// decimals
+func toRat(in any) (*big.Rat, bool) {
+	switch v := in.(type) {
+	case types.Decimal:
+		return v.Rat, true
+	case float64:
+		d, ok := types.DecimalFromFloat(v)
+		return d.Rat, ok
+	}
+	return nil, false
+}
+
+// describes why in can not be converted by toRat
+func ratError(in any) string {
+	if f, ok := in.(float64); ok {
+		return fmt.Sprintf("Can't promote %v to a decimal, only finite floats can be promoted", f)
+	}
+	return fmt.Sprintf("Expected value of type decimal or float, got %T", in)
+}
+
+// casts in to a rational number, promotes finite float64 values to decimals.
+// Panics for all other values, n is the node the value was produced by.
+func castDecimalPanic(in any, n types.Node) *big.Rat {
+	r, ok := toRat(in)
+	if !ok {
+		serror.AddNode(n, "Type error", "%s", ratError(in))
+		serror.Panic()
+	}
+	return r
+}
+
+// evaluates the statement n, counts its evaluation if coverage is recorded,
+// measures it if profiling and pauses before it if debugging
+func EvalStatement(n types.Node) any {
+	if consts.COVER {
+		// only registered statements are counted, excluding test blocks
+		if _, ok := consts.COVERAGE[n]; ok {
+			consts.COVERAGE[n]++
+		}
+	}
+	if consts.DEBUGGER != nil {
+		consts.DEBUGGER.Statement(n)
+	}
+	if consts.PROFILE {
+		profile.EnterStatement(n.GetSpan())
+		defer profile.LeaveStatement()
+	}
+	return n.Eval()
+}
+
+// compares a and b for equality, decimals are compared by value and floats
+// are promoted if compared to a decimal. Panics at t if a float can not be
+// promoted.
+func Equals(a, b any, t *token.Token) bool {
+	if isDecimal(a) || isDecimal(b) {
+		if !isNumber(a) || !isNumber(b) {
+			return false
+		}
+		ra, ok := toRat(a)
+		if !ok {
+			serror.Add(t, "Type error", "%s", ratError(a))
+			serror.Panic()
+		}
+		rb, ok := toRat(b)
+		if !ok {
+			serror.Add(t, "Type error", "%s", ratError(b))
+			serror.Panic()
+		}
+		return ra.Cmp(rb) == 0
+	}
+	return a == b
+}
+
+func isNumber(in any) bool {
+	switch in.(type) {
+	case float64, types.Decimal:
+		return true
+	}
+	return false
+}
+
+// applies floatOp or decimalOp to all children from left to right, switches
+// to decimal arithmetic as soon as a decimal is encountered
+func arithmetic(children []types.Node, floatOp func(a, b float64) float64, decimalOp func(z, a, b *big.Rat) *big.Rat) any {
+	res := 0.0
+	var dec *big.Rat
+	for i, c := range children {
+		v := c.Eval()
+		if dec == nil && isDecimal(v) {
+			// promote the result computed so far to a decimal
+			dec = castDecimalPanic(res, c)
+		}
+		if dec != nil {
+			if i == 0 {
+				dec.Set(castDecimalPanic(v, c))
+			} else {
+				decimalOp(dec, dec, castDecimalPanic(v, c))
+			}
+		} else if i == 0 {
+			res = castFloatPanic(v, c)
+		} else {
+			res = floatOp(res, castFloatPanic(v, c))
+		}
+	}
+	if dec != nil {
+		return types.Decimal{Rat: dec}
+	}
+	return res
+}
@@ -10,9 +10,11 @@ import (
 // defining a variable
 type Var struct {
 	Token       *token.Token
+	Span        token.Span
 	IndexAssign bool
 	Ident       *Ident
 	Value       []types.Node
+	Doc         string // content of the doc comments preceding the definition
 }
 
 func (v *Var) GetChildren() []types.Node {
@@ -27,6 +29,10 @@ func (v *Var) GetToken() *token.Token {
 	return v.Token
 }
 
+func (v *Var) GetSpan() token.Span {
+	return v.Span
+}
+
 func (v *Var) Eval() any {
 	var val any
 	if len(v.Value) > 1 {
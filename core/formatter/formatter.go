@@ -0,0 +1,316 @@
+// Formats sophia source code in the canonical style used by `sophia fmt`.
+//
+// The formatter works on the token stream including comments, thus comments
+// are preserved. Line breaks between the elements of a statement are kept,
+// while indentation and the whitespace between elements is normalized:
+//
+//   - each top level statement starts on its own line
+//   - at most one blank line is kept between elements
+//   - elements on a new line are indented by two spaces for each statement
+//     opened but not yet closed on the line the statement starts on
+//   - closing braces are placed directly after the last element, except for
+//     statements, arrays and objects written in block style
+//   - literals, such as strings, numbers and the text of template strings,
+//     as well as block comments are kept as written
+//   - expressions interpolated in template strings are formatted on a single
+//     line, their format specifiers are kept as written
+package formatter
+
+import (
+	"strings"
+
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+)
+
+// amount of spaces used for each level of indentation
+const INDENT = 2
+
+// element of the formatted source, either a literal, a comment or a
+// statement, array or object enclosed in braces
+type node struct {
+	first    *token.Token
+	last     *token.Token
+	children []*node
+	group    bool
+	// tokens between the quotes of a template string
+	inner []*token.Token
+}
+
+func (n *node) comment() bool {
+	return n.first.Type == token.COMMENT || n.first.Type == token.DOC_COMMENT
+}
+
+// reports whether the node is a ;; or ;;; comment, which has to be followed
+// by a line break
+func (n *node) lineComment() bool {
+	return n.comment() && !strings.HasPrefix(n.first.Raw, "#|")
+}
+
+type formatter struct {
+	src    string
+	tokens []*token.Token
+	pos    int
+	b      strings.Builder
+	line   int  // current output line
+	indent int  // indentation of the current output line
+	opened int  // statements opened on the current output line and not yet closed
+	inline bool // line breaks are replaced by spaces, used for interpolations
+}
+
+// formats src, errors are reported via serror, check serror.HasErrors
+// before using the result
+func Format(src string, filename string) string {
+	l := lexer.New(strings.NewReader(src), filename)
+	l.KeepComments()
+	tokens := l.Lex()
+	if serror.HasErrors() {
+		return src
+	}
+	f := &formatter{
+		src:    src,
+		tokens: tokens,
+	}
+	nodes := f.parse(nil)
+	if serror.HasErrors() {
+		return src
+	}
+	f.nodes(nodes, 0, true, false)
+	if f.b.Len() != 0 {
+		f.b.WriteByte('\n')
+	}
+	return f.b.String()
+}
+
+// groups the token into nodes until the closing token of open is found
+func (f *formatter) parse(open *token.Token) []*node {
+	nodes := make([]*node, 0)
+	for {
+		t := f.tokens[f.pos]
+		switch t.Type {
+		case token.EOF:
+			if open != nil {
+				serror.Add(open, "Unbalanced braces", "Missing closing brace for %q", open.Raw)
+			}
+			return nodes
+		case token.LEFT_BRACE, token.LEFT_BRACKET, token.LEFT_CURLY:
+			f.pos++
+			children := f.parse(t)
+			nodes = append(nodes, &node{
+				first:    t,
+				last:     f.tokens[f.pos],
+				children: children,
+				group:    true,
+			})
+			if f.tokens[f.pos].Type == token.EOF {
+				// missing closing brace, already reported
+				return nodes
+			}
+		case token.RIGHT_BRACE, token.RIGHT_BRACKET, token.RIGHT_CURLY:
+			if open == nil || closing(open.Type) != t.Type {
+				serror.Add(t, "Unbalanced braces", "Unexpected closing brace %q", t.Raw)
+			}
+			return nodes
+		case token.TEMPLATE_STRING:
+			// the lexer guarantees the closing TEMPLATE_STRING token to
+			// exist
+			n := &node{first: t}
+			for f.pos++; f.tokens[f.pos].Type != token.TEMPLATE_STRING; f.pos++ {
+				n.inner = append(n.inner, f.tokens[f.pos])
+			}
+			n.last = f.tokens[f.pos]
+			nodes = append(nodes, n)
+		default:
+			nodes = append(nodes, &node{first: t, last: t})
+		}
+		f.pos++
+	}
+}
+
+// returns the type of the token closing the opening brace ttype
+func closing(ttype int) int {
+	switch ttype {
+	case token.LEFT_BRACE:
+		return token.RIGHT_BRACE
+	case token.LEFT_BRACKET:
+		return token.RIGHT_BRACKET
+	default:
+		return token.RIGHT_CURLY
+	}
+}
+
+// writes the nodes, separated by a space or a line break, elements starting
+// on a new line are indented by indent. Each top level node is placed on its
+// own line.
+func (f *formatter) nodes(nodes []*node, indent int, topLevel bool, object bool) {
+	for i, n := range nodes {
+		if i > 0 {
+			prev := nodes[i-1]
+			lines := n.first.Line - prev.last.End.Line
+			glue := glued(prev, n, object)
+			if !f.inline && (prev.lineComment() || (lines > 0 || topLevel && !n.comment()) && !glue) {
+				f.newline(indent, lines > 1)
+			} else if !glue {
+				f.write(" ")
+			}
+		}
+		f.node(n)
+	}
+}
+
+func (f *formatter) node(n *node) {
+	if n.first.Type == token.TEMPLATE_STRING {
+		f.write(f.template(n))
+		return
+	}
+	if !n.group {
+		f.write(f.text(n))
+		return
+	}
+	lineIndent := f.indent
+	f.write(n.first.Raw)
+	line := f.line
+	f.opened++
+	indent := f.indent + INDENT*f.opened
+	if n.block() && !f.inline {
+		// block style: the elements are placed on their own lines and
+		// the closing brace is aligned with the line of the opening brace
+		indent = lineIndent + INDENT
+		f.newline(indent, false)
+		f.nodes(n.children, indent, false, n.first.Type == token.LEFT_CURLY)
+		f.newline(lineIndent, false)
+	} else {
+		f.nodes(n.children, indent, false, n.first.Type == token.LEFT_CURLY)
+		if !f.inline && len(n.children) != 0 && n.children[len(n.children)-1].lineComment() {
+			f.newline(indent-INDENT, false)
+		}
+	}
+	f.write(n.last.Raw)
+	if f.line == line {
+		f.opened--
+	}
+}
+
+// reports whether the elements of the group start on the line after the
+// opening brace and the closing brace is placed on its own line, such as:
+//
+//	(let person {
+//	  name: "anon"
+//	})
+func (n *node) block() bool {
+	if len(n.children) == 0 {
+		return false
+	}
+	first, last := n.children[0], n.children[len(n.children)-1]
+	return first.first.Line > n.first.Line && n.last.Line > last.last.End.Line
+}
+
+// returns the template string n with each interpolated expression formatted
+// on a single line. The lexer emits the literal parts and the tokens of the
+// expressions between the quotes, an expression starts at the first token
+// following a {, its tokens are only separated by whitespace.
+func (f *formatter) template(n *node) string {
+	b := strings.Builder{}
+	cursor := n.first.Pos
+	prev := n.first
+	expr := make([]*token.Token, 0)
+	// writes the text up to the expression, the expression and its format
+	// specifier
+	flush := func() {
+		if len(expr) == 0 {
+			return
+		}
+		open := cursor + strings.LastIndexByte(f.src[cursor:expr[0].Pos], '{')
+		b.WriteString(f.src[cursor : open+1])
+		last := expr[len(expr)-1]
+		var format *token.Token
+		if last.Type == token.FORMAT {
+			format, expr = last, expr[:len(expr)-1]
+		}
+		sub := &formatter{
+			src:    f.src,
+			tokens: append(expr, &token.Token{Type: token.EOF}),
+			inline: true,
+		}
+		sub.nodes(sub.parse(nil), 0, false, false)
+		formatted := sub.b.String()
+		if strings.HasPrefix(formatted, "{") {
+			// {{ is an escaped brace, not an interpolated object
+			formatted = " " + formatted + " "
+		}
+		b.WriteString(formatted)
+		if format != nil {
+			b.WriteString(f.src[format.Pos:format.End.Offset])
+			cursor = format.End.Offset
+		} else {
+			// drop the whitespace before the closing }
+			cursor = last.End.Offset + strings.IndexByte(f.src[last.End.Offset:], '}')
+		}
+		expr = expr[:0]
+	}
+	for _, t := range append(n.inner, n.last) {
+		gap := strings.TrimRight(f.src[prev.End.Offset:t.Pos], " \t\r\n")
+		switch {
+		case t == n.last:
+			flush()
+		case strings.HasSuffix(gap, "{"):
+			flush()
+			expr = append(expr, t)
+		case len(expr) != 0 && gap == "":
+			expr = append(expr, t)
+		default:
+			// literal part of the template string
+			flush()
+		}
+		prev = t
+	}
+	b.WriteString(f.src[cursor:n.last.End.Offset])
+	return b.String()
+}
+
+// returns the source code of n as written, without trailing whitespace
+func (f *formatter) text(n *node) string {
+	return strings.TrimRight(f.src[n.first.Pos:n.last.End.Offset], " \t\r")
+}
+
+// reports whether b directly follows a without whitespace, such as the index
+// access a#[0], module access a::b or type annotation a:float. Keys and values
+// of objects are separated by a colon and a space: {a: 1}
+func glued(a, b *node, object bool) bool {
+	switch b.first.Type {
+	case token.COLON, token.HASHTAG, token.DOUBLE_COLON:
+		return true
+	}
+	switch a.last.Type {
+	case token.HASHTAG, token.DOUBLE_COLON:
+		return true
+	case token.COLON:
+		return !object
+	}
+	// chained index access: a#[0][1]
+	return a.last.Type == token.RIGHT_BRACKET && b.first.Type == token.LEFT_BRACKET && a.last.End.Offset == b.first.Pos
+}
+
+func (f *formatter) write(s string) {
+	f.b.WriteString(s)
+	if i := strings.LastIndexByte(s, '\n'); i != -1 {
+		// multi line literals and comments
+		f.line += strings.Count(s, "\n")
+		rest := s[i+1:]
+		f.indent = len(rest) - len(strings.TrimLeft(rest, " \t"))
+		f.opened = 0
+	}
+}
+
+func (f *formatter) newline(indent int, blank bool) {
+	if blank {
+		f.b.WriteByte('\n')
+		f.line++
+	}
+	f.b.WriteByte('\n')
+	f.line++
+	f.b.WriteString(strings.Repeat(" ", indent))
+	f.indent = indent
+	f.opened = 0
+}
@@ -0,0 +1,98 @@
+package formatter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+func format(t *testing.T, in string) string {
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	out := Format(in, "test")
+	if serror.HasErrors() {
+		t.Fatalf("failed to format %q: %v", in, serror.Default().Errors())
+	}
+	return out
+}
+
+func TestFormat(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp string
+	}{
+		{in: "(println   \"hi\"  )", exp: "(println \"hi\")\n"},
+		{in: "(let a 1) (let b 2)", exp: "(let a 1)\n(let b 2)\n"},
+		{in: "(let a 1)\n\n\n\n(let b 2)\n\n", exp: "(let a 1)\n\n(let b 2)\n"},
+		{in: "(fun sq [n]\n        (* n n))", exp: "(fun sq [n]\n  (* n n))\n"},
+		{in: "(if (and a\nb)\n(println))", exp: "(if (and a\n    b)\n  (println))\n"},
+		{in: "(let a [ 1 2  3 ])", exp: "(let a [1 2 3])\n"},
+		{in: "(let o {a : 1 b:{c:2}})", exp: "(let o {a: 1 b: {c: 2}})\n"},
+		{in: "(fun f: float [a : float b:string] a)", exp: "(fun f:float [a:float b:string] a)\n"},
+		{in: "(let o {\n      a: 1\n      b: 2\n    })", exp: "(let o {\n  a: 1\n  b: 2\n})\n"},
+		{in: "(println a # [0][1] b#[\"c\"])", exp: "(println a#[0][1] b#[\"c\"])\n"},
+		{in: "(println 'a {(+ 1  2):>8.2f} b'  `raw\n  string`)", exp: "(println 'a {(+ 1 2):>8.2f} b' `raw\n  string`)\n"},
+		{in: "(println '{  a  }  {{x}} {a#[ 0 ]}{(* a\n   2)}')", exp: "(println '{a}  {{x}} {a#[0]}{(* a 2)}')\n"},
+		{in: "(println '{{ k:  1 }}{  {k: 1}}')", exp: "(println '{{ k:  1 }}{ {k: 1} }')\n"},
+		{in: "(println \"\\u{41}\\n\" 0x1F 1_000 0.1d)", exp: "(println \"\\u{41}\\n\" 0x1F 1_000 0.1d)\n"},
+	}
+	for _, test := range tests {
+		t.Run(test.in, func(t *testing.T) {
+			if out := format(t, test.in); out != test.exp {
+				t.Errorf("wanted:\n%s\ngot:\n%s", test.exp, out)
+			}
+		})
+	}
+}
+
+func TestFormatComments(t *testing.T) {
+	in := ";; leading\n;;; doc\n(fun f [a]   ;; trailing\n    #| block\n  comment |# a\n      ;; last\n)\n(let a 1) ;; after"
+	exp := ";; leading\n;;; doc\n(fun f [a] ;; trailing\n  #| block\n  comment |# a\n  ;; last\n)\n(let a 1) ;; after\n"
+	if out := format(t, in); out != exp {
+		t.Errorf("wanted:\n%s\ngot:\n%s", exp, out)
+	}
+}
+
+func TestFormatErrors(t *testing.T) {
+	tests := []string{
+		"(println 1",
+		"(println 1))",
+		"(let a [1 2)]",
+		"(println \"unterminated)",
+	}
+	for _, in := range tests {
+		t.Run(in, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+			Format(in, "test")
+			if !serror.HasErrors() {
+				t.Errorf("expected errors for %q", in)
+			}
+		})
+	}
+}
+
+// formatting the examples has to result in formatted examples, formatting
+// them again must not change anything
+func TestFormatExamplesIdempotent(t *testing.T) {
+	files, err := filepath.Glob("../../examples/*.phia")
+	if err != nil || len(files) == 0 {
+		t.Fatalf("failed to find examples: %v", err)
+	}
+	for _, file := range files {
+		t.Run(filepath.Base(file), func(t *testing.T) {
+			content, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			first := format(t, string(content))
+			if first != string(content) {
+				t.Errorf("example is not formatted, run 'sophia fmt -w examples'")
+			}
+			if second := format(t, first); second != first {
+				t.Errorf("formatting is not idempotent, wanted:\n%s\ngot:\n%s", first, second)
+			}
+		})
+	}
+}
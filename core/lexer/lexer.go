@@ -2,303 +2,631 @@ package lexer
 
 import (
 	"bufio"
+	"errors"
+	"fmt"
 	"io"
+	"strconv"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 
 	"github.com/xnacly/sophia/core/serror"
 	"github.com/xnacly/sophia/core/token"
 )
 
 type Lexer struct {
-	reader  *bufio.Reader
-	pos     int
-	chr     rune
-	line    int
-	linepos int
+	reader *bufio.Reader
+	file   string
+	chr    rune
+	size   int // byte size of chr
+	offset int
+	line   int
+	col    int
+	// emit COMMENT token instead of skipping comments
+	comments bool
 }
 
-func New(r io.Reader) *Lexer {
+func New(r io.Reader, filename string) *Lexer {
 	in := bufio.NewReader(r)
 	if in.Size() == 0 {
-		serror.Add(&token.Token{LinePos: 0, Raw: " "}, "Unexpected end of file", "Source empty")
+		serror.Add(&token.Token{LinePos: 0, Raw: " ", File: filename}, "Unexpected end of file", "Source empty")
 		return &Lexer{}
 	}
 
 	l := &Lexer{
-		reader:  in,
-		pos:     0,
-		line:    0,
-		linepos: 0,
+		reader: in,
+		file:   filename,
 	}
 	l.advance()
 	return l
 }
 
+// configures the lexer to emit COMMENT token for regular comments, used by
+// tooling which has to preserve comments, such as the formatter
+func (l *Lexer) KeepComments() {
+	l.comments = true
+}
+
+// returns the position of the current character
+func (l *Lexer) position() token.Position {
+	return token.Position{
+		Offset: l.offset,
+		Line:   l.line,
+		Column: l.col,
+	}
+}
+
+// creates a token of type ttype starting at the current character, call
+// l.end once the token is consumed
+func (l *Lexer) start(ttype int) *token.Token {
+	return &token.Token{
+		Pos:     l.offset,
+		Line:    l.line,
+		LinePos: l.col,
+		File:    l.file,
+		Type:    ttype,
+	}
+}
+
+// marks the current character as the end of t
+func (l *Lexer) end(t *token.Token) *token.Token {
+	t.End = l.position()
+	return t
+}
+
 func (l *Lexer) Lex() []*token.Token {
 	t := make([]*token.Token, 0)
 	for l.chr != 0 {
-		ttype := token.UNKNOWN
+		t = l.lexToken(t)
+	}
+	eof := l.start(token.EOF)
+	eof.Raw = " "
+	t = append(t, l.end(eof))
+	return t
+}
 
-		switch l.chr {
-		case '+':
-			if l.peek() == '+' {
-				ttype = token.MERGE
-				l.advance()
-			} else {
-				ttype = token.ADD
-			}
-		case '-':
-			if unicode.IsDigit(l.peek()) {
-				if tok, err := l.float(); err == nil {
-					t = append(t, tok)
-				} else {
-					serror.Add(tok, "Invalid floating point number", "")
-				}
-				continue
-			} else {
-				ttype = token.SUB
-			}
-		case '/':
-			ttype = token.DIV
-		case '#':
-			ttype = token.HASHTAG
-		case '*':
-			ttype = token.MUL
-		case '%':
-			ttype = token.MOD
-		case '(':
-			ttype = token.LEFT_BRACE
-		case ')':
-			ttype = token.RIGHT_BRACE
-		case '{':
-			ttype = token.LEFT_CURLY
-		case '}':
-			ttype = token.RIGHT_CURLY
-		case '[':
-			ttype = token.LEFT_BRACKET
-		case ']':
-			ttype = token.RIGHT_BRACKET
-		case ':':
-			if l.peek() == ':' {
-				ttype = token.DOUBLE_COLON
-				l.advance()
+// lexes the token at the current position and appends it to t, skips
+// whitespace and comments
+func (l *Lexer) lexToken(t []*token.Token) []*token.Token {
+	tok := l.start(token.UNKNOWN)
+	raw := string(l.chr)
+
+	switch l.chr {
+	case '+':
+		if l.peek() == '+' {
+			tok.Type = token.MERGE
+			raw = "++"
+			l.advance()
+		} else {
+			tok.Type = token.ADD
+		}
+	case '-':
+		if isDigit(l.peek()) || l.peekFraction() {
+			if tok, err := l.float(); err == nil {
+				t = append(t, tok)
 			} else {
-				ttype = token.COLON
+				serror.Add(tok, "Invalid numeric literal", "%q is not a valid number: %s.", tok.Raw, err)
 			}
-		case '.':
-			ttype = token.DOT
-		case '=':
-			ttype = token.EQUAL
-		case '<':
-			ttype = token.LT
-		case '>':
-			ttype = token.GT
-		case '\'':
-			t = append(t, l.templateString()...)
-			continue
-		case ' ', '\t', '\r', '\n':
-			if l.chr == '\n' {
-				l.linepos = -1
-				l.line++
+			return t
+		} else {
+			tok.Type = token.SUB
+		}
+	case '/':
+		tok.Type = token.DIV
+	case '#':
+		if l.peek() == '|' {
+			if c := l.blockComment(); l.comments {
+				t = append(t, c)
 			}
+			return t
+		}
+		tok.Type = token.HASHTAG
+	case '*':
+		tok.Type = token.MUL
+	case '%':
+		tok.Type = token.MOD
+	case '(':
+		tok.Type = token.LEFT_BRACE
+	case ')':
+		tok.Type = token.RIGHT_BRACE
+	case '{':
+		tok.Type = token.LEFT_CURLY
+	case '}':
+		tok.Type = token.RIGHT_CURLY
+	case '[':
+		tok.Type = token.LEFT_BRACKET
+	case ']':
+		tok.Type = token.RIGHT_BRACKET
+	case ':':
+		if l.peek() == ':' {
+			tok.Type = token.DOUBLE_COLON
+			raw = "::"
 			l.advance()
-			continue
-		case '"':
-			t = append(t, l.string())
-			continue
-		case ';':
-			if l.peek() == ';' {
-				for l.chr != '\n' && l.chr != 0 {
-					l.advance()
-				}
-				continue
-			}
-		default:
-			if unicode.IsLetter(l.chr) {
-				t = append(t, l.ident())
-				continue
-			} else if unicode.IsDigit(l.chr) {
-				if tok, err := l.float(); err == nil {
-					t = append(t, tok)
-				} else {
-					serror.Add(tok, "Invalid floating point number", "")
-				}
-				continue
+		} else {
+			tok.Type = token.COLON
+		}
+	case '.':
+		// floats may omit the integer part: .5
+		if isDigit(l.peek()) {
+			if tok, err := l.float(); err == nil {
+				t = append(t, tok)
+			} else {
+				serror.Add(tok, "Invalid numeric literal", "%q is not a valid number: %s.", tok.Raw, err)
 			}
+			return t
 		}
-
-		if ttype == token.UNKNOWN {
-			serror.Add(&token.Token{
-				Pos:     l.pos,
-				Type:    ttype,
-				Line:    l.line,
-				LinePos: l.linepos,
-				Raw:     string(l.chr),
-			}, "Unknown character", "Unexpected \"%c\"", l.chr)
+		tok.Type = token.DOT
+	case '=':
+		tok.Type = token.EQUAL
+	case '<':
+		tok.Type = token.LT
+	case '>':
+		tok.Type = token.GT
+	case '\'':
+		return append(t, l.templateString()...)
+	case ' ', '\t', '\r', '\n':
+		l.advance()
+		return t
+	case '"':
+		return append(t, l.string())
+	case '`':
+		return append(t, l.rawString())
+	case ';':
+		if l.peek() == ';' {
+			return l.lineComment(t)
 		}
+	default:
+		if unicode.IsLetter(l.chr) {
+			return append(t, l.ident())
+		} else if isDigit(l.chr) {
+			if tok, err := l.float(); err == nil {
+				t = append(t, tok)
+			} else {
+				serror.Add(tok, "Invalid numeric literal", "%q is not a valid number: %s.", tok.Raw, err)
+			}
+			return t
+		}
+	}
 
-		t = append(t, &token.Token{
-			Pos:     l.pos,
-			Type:    ttype,
-			Line:    l.line,
-			LinePos: l.linepos,
-			Raw:     string(l.chr),
-		})
-
-		l.advance()
+	l.advance()
+	tok.Raw = raw
+	l.end(tok)
+	if tok.Type == token.UNKNOWN {
+		serror.Add(tok, "Unknown character", "Unexpected %q", raw)
 	}
-	t = append(t, &token.Token{
-		Type:    token.EOF,
-		Line:    l.line,
-		LinePos: l.linepos,
-		Raw:     " ",
-	})
-	return t
+	return append(t, tok)
 }
 
+// lexes a template string, such as 'Hi {name}, you owe {(* price 2):.2f}€',
+// into the enclosing TEMPLATE_STRING token, STRING token for literal parts and
+// the token of each interpolated expression, optionally followed by a FORMAT
+// token
 func (l *Lexer) templateString() []*token.Token {
-	el := make([]*token.Token, 0)
-	el = append(el, &token.Token{
-		Type:    token.TEMPLATE_STRING,
-		LinePos: l.linepos,
-		Pos:     l.pos,
-		Line:    l.line,
-		Raw:     "",
-	})
+	start := l.start(token.TEMPLATE_STRING)
+	l.advance() // skip '
+	l.end(start)
+	el := []*token.Token{start}
 	b := strings.Builder{}
+	var part *token.Token
 
-	l.advance() // skip '
+	// appends the currently buffered literal part
+	flush := func() {
+		if b.Len() != 0 {
+			part.Raw = b.String()
+			el = append(el, l.end(part))
+			b.Reset()
+		}
+	}
 
-	for {
-		if l.chr == '}' {
+	for l.chr != '\'' {
+		if b.Len() == 0 {
+			part = l.start(token.STRING)
+		}
+		switch l.chr {
+		case 0:
+			serror.Add(start, "Unterminated template string", "Consider closing the template string via '")
+			return []*token.Token{}
+		case '\\':
+			l.escape(&b)
+		case '{':
+			if l.peek() == '{' {
+				b.WriteRune('{')
+				l.advance()
+				l.advance()
+				continue
+			}
+			flush()
+			el = l.interpolation(el)
+		case '}':
+			if l.peek() == '}' {
+				b.WriteRune('}')
+				l.advance()
+				l.advance()
+				continue
+			}
+			t := l.start(token.RIGHT_CURLY)
+			t.Raw = "}"
 			l.advance()
-			continue
-		} else if l.chr == '{' {
+			serror.Add(l.end(t), "Unmatched '}' in template string", "Consider escaping the '}' via '}}'")
+		default:
+			b.WriteRune(l.chr)
 			l.advance()
-			if b.Len() != 0 {
-				el = append(el, &token.Token{
-					Pos:     l.pos - (len(b.String()) + 2),
-					Type:    token.STRING,
-					Raw:     b.String(),
-					Line:    l.line,
-					LinePos: l.linepos,
-				})
-				b.Reset()
-			}
-			el = append(el, l.ident())
+		}
+	}
+	flush()
+
+	end := l.start(token.TEMPLATE_STRING)
+	l.advance() // skip '
+	return append(el, l.end(end))
+}
+
+// lexes the expression enclosed in {} inside of a template string and the
+// optional format specifier following it, such as {price:8.2f}
+func (l *Lexer) interpolation(el []*token.Token) []*token.Token {
+	open := l.start(token.LEFT_CURLY)
+	open.Raw = "{"
+	l.advance() // skip {
+	l.end(open)
+	exprStart := len(el)
+	hasFormat := false
+	depth := 0
+	for depth != 0 || l.chr != '}' {
+		if l.chr == 0 || l.chr == '\'' {
+			serror.Add(open, "Unterminated interpolation in template string", "Consider closing the interpolation via '}'")
+			return el
+		}
+		if depth == 0 && l.chr == ':' && l.peek() != ':' && !hasFormat {
+			el = append(el, l.formatSpecifier())
+			hasFormat = true
 			continue
-		} else if l.chr == '\n' || l.chr == 0 {
-			var errEl *token.Token
-			if len(el) > 1 {
-				errEl = el[len(el)-1]
-			}
-			serror.Add(errEl, "Unexpected new line or end of file in template string", "Consider closing the template string via ' or omitting the inserted new line")
-			return []*token.Token{}
-		} else if l.chr == '\'' {
-			if b.Len() != 0 {
-				el = append(el, &token.Token{
-					Pos:     l.pos - (len(b.String()) + 2),
-					LinePos: l.linepos,
-					Type:    token.STRING,
-					Raw:     b.String(),
-					Line:    l.line,
-				})
-				b.Reset()
-			}
+		} else if hasFormat {
 			break
 		}
+		before := len(el)
+		el = l.lexToken(el)
+		if len(el) > before {
+			switch el[len(el)-1].Type {
+			case token.LEFT_CURLY:
+				depth++
+			case token.RIGHT_CURLY:
+				depth--
+			}
+		}
+	}
+	if l.chr != '}' {
+		serror.Add(open, "Unterminated interpolation in template string", "Consider closing the interpolation via '}'")
+		return el
+	}
+	if len(el) == exprStart || el[exprStart].Type == token.FORMAT {
+		serror.Add(open, "Empty interpolation in template string", "Consider inserting an expression between '{' and '}' or escaping the '{' via '{{'")
+	}
+	l.advance() // skip }
+	return el
+}
+
+// lexes the format specifier of an interpolation, starting at the ':'
+func (l *Lexer) formatSpecifier() *token.Token {
+	t := l.start(token.FORMAT)
+	l.advance() // skip :
+	b := strings.Builder{}
+	for l.chr != '}' && l.chr != '\'' && l.chr != '\n' && l.chr != 0 {
 		b.WriteRune(l.chr)
 		l.advance()
 	}
+	t.Raw = b.String()
+	return l.end(t)
+}
 
-	if l.chr == '\'' {
+// skips a ;; comment, appends a DOC_COMMENT token to t if the comment is a
+// ;;; doc comment or a COMMENT token if comments are kept
+func (l *Lexer) lineComment(t []*token.Token) []*token.Token {
+	doc := l.start(token.DOC_COMMENT)
+	raw := strings.Builder{}
+	semicolons := 0
+	for l.chr == ';' {
+		semicolons++
+		raw.WriteRune(l.chr)
 		l.advance()
 	}
+	b := strings.Builder{}
+	for l.chr != '\n' && l.chr != 0 {
+		b.WriteRune(l.chr)
+		l.advance()
+	}
+	if semicolons != 3 {
+		if l.comments {
+			doc.Type = token.COMMENT
+			doc.Raw = strings.TrimRight(raw.String()+b.String(), " \t\r")
+			t = append(t, l.end(doc))
+		}
+		return t
+	}
+	doc.Raw = strings.TrimPrefix(strings.TrimRight(b.String(), " \t\r"), " ")
+	return append(t, l.end(doc))
+}
 
-	el = append(el, &token.Token{
-		Type:    token.TEMPLATE_STRING,
-		LinePos: l.linepos,
-		Pos:     l.pos,
-		Line:    l.line,
-		Raw:     "",
-	})
-	return el
+// skips a block comment enclosed in #| and |#, block comments can be nested.
+// Returns the comment as a COMMENT token.
+func (l *Lexer) blockComment() *token.Token {
+	start := l.start(token.UNKNOWN)
+	start.Raw = "#|"
+	comment := l.start(token.COMMENT)
+	b := strings.Builder{}
+	depth := 0
+	for l.chr != 0 {
+		if l.chr == '#' && l.peek() == '|' {
+			depth++
+			b.WriteRune(l.chr)
+			l.advance()
+			if depth == 1 {
+				l.end(start)
+			}
+		} else if l.chr == '|' && l.peek() == '#' {
+			depth--
+			b.WriteRune(l.chr)
+			l.advance()
+		}
+		b.WriteRune(l.chr)
+		l.advance()
+		if depth == 0 {
+			comment.Raw = b.String()
+			return l.end(comment)
+		}
+	}
+	serror.Add(start, "Unterminated block comment", "Consider closing the block comment via |#")
+	comment.Raw = b.String()
+	return l.end(comment)
 }
 
 func (l *Lexer) string() *token.Token {
-	l.advance()
+	t := l.start(token.STRING)
+	l.advance() // skip "
 	b := strings.Builder{}
 	for l.chr != '"' && l.chr != 0 {
-		if l.chr == '\n' {
-			l.line++
-			l.linepos = -1
+		if l.chr == '\\' {
+			l.escape(&b)
+			continue
 		}
 		b.WriteRune(l.chr)
 		l.advance()
 	}
-	str := b.String()
+	t.Raw = b.String()
 	if l.chr != '"' {
-		serror.Add(&token.Token{
-			Pos:     l.pos - (len(str) + 2),
-			Type:    token.STRING,
-			Raw:     "\"" + str,
-			Line:    l.line,
-			LinePos: l.linepos - len(str),
-		}, "Unterminated string", "Consider closing the string via \"")
+		err := *l.end(t)
+		err.Raw = "\"" + t.Raw
+		serror.Add(&err, "Unterminated string", "Consider closing the string via \"")
 	} else {
 		l.advance()
 	}
+	return l.end(t)
+}
 
-	return &token.Token{
-		Pos:     l.pos - (len(str) + 2),
-		Type:    token.STRING,
-		LinePos: l.linepos - len(str) - 1,
-		Raw:     str,
-		Line:    l.line,
+// raw strings are enclosed in backticks, may span multiple lines and do not
+// support escape sequences
+func (l *Lexer) rawString() *token.Token {
+	t := l.start(token.STRING)
+	l.advance() // skip `
+	b := strings.Builder{}
+	for l.chr != '`' && l.chr != 0 {
+		b.WriteRune(l.chr)
+		l.advance()
+	}
+	t.Raw = b.String()
+	if l.chr != '`' {
+		err := *l.end(t)
+		err.Raw = "`" + t.Raw
+		serror.Add(&err, "Unterminated raw string", "Consider closing the raw string via `")
+	} else {
+		l.advance()
 	}
+	return l.end(t)
+}
+
+// consumes the escape sequence starting at the current backslash and writes
+// the resulting character into b, reports invalid escape sequences
+func (l *Lexer) escape(b *strings.Builder) {
+	t := l.start(token.STRING)
+	t.Raw = "\\"
+	l.advance() // skip \
+	switch l.chr {
+	case 0:
+		// unterminated string, reported by the caller
+		return
+	case '"', '\'', '\\':
+		b.WriteRune(l.chr)
+	case 'n':
+		b.WriteRune('\n')
+	case 't':
+		b.WriteRune('\t')
+	case 'r':
+		b.WriteRune('\r')
+	case 'u':
+		if r, ok := l.unicodeEscape(t); ok {
+			b.WriteRune(r)
+		}
+		return
+	default:
+		t.Raw += string(l.chr)
+		l.advance()
+		serror.Add(l.end(t), "Invalid escape sequence", "Unknown escape sequence '%s', expected any of \\\", \\', \\\\, \\n, \\t, \\r or \\u{...}", t.Raw)
+		return
+	}
+	l.advance()
+}
+
+// consumes a unicode escape sequence of the form u{1F600}, t contains the
+// position of the escape sequence and is extended for error reporting
+func (l *Lexer) unicodeEscape(t *token.Token) (rune, bool) {
+	t.Raw += "u"
+	l.advance() // skip u
+	if l.chr != '{' {
+		serror.Add(l.end(t), "Invalid escape sequence", "Expected '{' after \\u, unicode escape sequences are written as \\u{1F600}")
+		return 0, false
+	}
+	t.Raw += "{"
+	l.advance() // skip {
+	digits := strings.Builder{}
+	for l.chr != '}' && l.chr != '"' && l.chr != '\'' && l.chr != '\n' && l.chr != 0 {
+		digits.WriteRune(l.chr)
+		t.Raw += string(l.chr)
+		l.advance()
+	}
+	if l.chr != '}' {
+		serror.Add(l.end(t), "Invalid escape sequence", "Unterminated unicode escape sequence, consider closing it via '}'")
+		return 0, false
+	}
+	t.Raw += "}"
+	l.advance() // skip }
+	l.end(t)
+	hex := digits.String()
+	if len(hex) == 0 || len(hex) > 6 {
+		serror.Add(t, "Invalid escape sequence", "Expected 1 to 6 hexadecimal digits in unicode escape sequence, got %d", len(hex))
+		return 0, false
+	}
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		serror.Add(t, "Invalid escape sequence", "%q is not a hexadecimal number", hex)
+		return 0, false
+	}
+	if !utf8.ValidRune(rune(value)) {
+		serror.Add(t, "Invalid escape sequence", "%q is not a valid unicode code point", hex)
+		return 0, false
+	}
+	return rune(value), true
 }
 
 func (l *Lexer) ident() *token.Token {
+	t := l.start(token.IDENT)
 	builder := strings.Builder{}
 	for unicode.IsLetter(l.chr) || l.chr == '_' || unicode.IsDigit(l.chr) || l.chr == '-' {
 		builder.WriteRune(l.chr)
 		l.advance()
 	}
-	str := builder.String()
-	ttype := token.UNKNOWN
-	switch str {
+	t.Raw = builder.String()
+	switch t.Raw {
 	case "true", "false":
-		ttype = token.BOOL
-	default:
-		ttype = token.IDENT
-	}
-	if tokenType, ok := token.KEYWORD_MAP[str]; ok {
-		ttype = tokenType
+		t.Type = token.BOOL
 	}
-	return &token.Token{
-		Pos:     l.pos - len(str),
-		Type:    ttype,
-		LinePos: l.linepos - len(str),
-		Raw:     str,
-		Line:    l.line,
+	if tokenType, ok := token.KEYWORD_MAP[t.Raw]; ok {
+		t.Type = tokenType
 	}
+	return l.end(t)
 }
 
+// lexes numeric literals, such as 12, -1.5, .5, 1_000, 1e-3, 2.5E+2, 0x1F, 0o17
+// and 0b1010, as well as decimals suffixed with d: 19.99d. Malformed literals
+// are consumed completely and returned with an error.
 func (l *Lexer) float() (*token.Token, error) {
-	builder := strings.Builder{}
-	for unicode.IsDigit(l.chr) || (l.chr == '.' && unicode.IsDigit(l.peek())) || l.chr == '_' || l.chr == 'e' || l.chr == '-' {
-		builder.WriteRune(l.chr)
+	t := l.start(token.FLOAT)
+	b := strings.Builder{}
+	if l.chr == '-' {
+		b.WriteRune(l.chr)
 		l.advance()
 	}
-	str := builder.String()
-	return &token.Token{
-		Pos:     l.pos - len(str),
-		Type:    token.FLOAT,
-		LinePos: l.linepos - len(str),
-		Raw:     str,
-		Line:    l.line,
-	}, nil
+
+	var err error
+	if prefix := unicode.ToLower(l.peek()); l.chr == '0' && (prefix == 'x' || prefix == 'o' || prefix == 'b') {
+		b.WriteRune(l.chr)
+		l.advance()
+		b.WriteRune(l.chr)
+		l.advance()
+		valid := isHexDigit
+		if prefix == 'o' {
+			valid = isOctalDigit
+		} else if prefix == 'b' {
+			valid = isBinaryDigit
+		}
+		var n int
+		n, err = l.digits(&b, valid)
+		if err == nil && n == 0 {
+			err = fmt.Errorf("expected digits after the %q prefix", "0"+string(prefix))
+		}
+	} else {
+		_, err = l.digits(&b, isDigit)
+		if err == nil && l.chr == '.' && isDigit(l.peek()) {
+			b.WriteRune(l.chr)
+			l.advance()
+			_, err = l.digits(&b, isDigit)
+		}
+		if err == nil && (l.chr == 'e' || l.chr == 'E') {
+			b.WriteRune(l.chr)
+			l.advance()
+			if l.chr == '+' || l.chr == '-' {
+				b.WriteRune(l.chr)
+				l.advance()
+			}
+			var n int
+			n, err = l.digits(&b, isDigit)
+			if err == nil && n == 0 {
+				err = errors.New("expected digits in the exponent")
+			}
+		}
+		// numbers suffixed with d are arbitrary precision decimals: 0.1d
+		if err == nil && l.chr == 'd' {
+			t.Type = token.DECIMAL
+			l.advance()
+		}
+	}
+
+	if err == nil && isNumberContinuation(l.chr) {
+		err = fmt.Errorf("unexpected %q in numeric literal", l.chr)
+	}
+	if err != nil {
+		// consume the remaining malformed literal to report its whole span
+		for isNumberContinuation(l.chr) {
+			b.WriteRune(l.chr)
+			l.advance()
+		}
+	}
+	t.Raw = b.String()
+	return l.end(t), err
+}
+
+// consumes digits accepted by valid, single underscores are allowed between
+// digits. Returns the amount of consumed digits.
+func (l *Lexer) digits(b *strings.Builder, valid func(rune) bool) (int, error) {
+	n := 0
+	for {
+		if valid(l.chr) {
+			n++
+		} else if l.chr == '_' {
+			if n == 0 || !valid(l.peek()) {
+				return n, errors.New("'_' must separate successive digits")
+			}
+		} else {
+			return n, nil
+		}
+		b.WriteRune(l.chr)
+		l.advance()
+	}
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isHexDigit(r rune) bool {
+	return isDigit(r) || (r >= 'a' && r <= 'f') || (r >= 'A' && r <= 'F')
+}
+
+func isOctalDigit(r rune) bool {
+	return r >= '0' && r <= '7'
+}
+
+func isBinaryDigit(r rune) bool {
+	return r == '0' || r == '1'
+}
+
+// characters not allowed to directly follow a numeric literal
+func isNumberContinuation(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '.' || r == '-' || r == '+'
+}
+
+// reports whether the characters following the current one are a dot and a
+// digit, such as in -.5
+func (l *Lexer) peekFraction() bool {
+	b, _ := l.reader.Peek(2)
+	return len(b) == 2 && b[0] == '.' && isDigit(rune(b[1]))
 }
 
 func (l *Lexer) peek() rune {
@@ -310,13 +638,22 @@ func (l *Lexer) peek() rune {
 	return cc
 }
 
+// moves to the next character, keeps track of the byte offset, the line and
+// the rune column of the current character
 func (l *Lexer) advance() {
-	cc, _, err := l.reader.ReadRune()
+	if l.chr == '\n' {
+		l.line++
+		l.col = 0
+	} else if l.size != 0 {
+		l.col++
+	}
+	l.offset += l.size
+	cc, size, err := l.reader.ReadRune()
 	if err != nil {
 		l.chr = 0
+		l.size = 0
 		return
 	}
-	l.pos++
-	l.linepos++
 	l.chr = cc
+	l.size = size
 }
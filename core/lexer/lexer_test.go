@@ -14,7 +14,7 @@ func TestLexerHelloWorld(t *testing.T) {
 	in := `(println "Hello World!")`
 
 	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
-	l := New(strings.NewReader(in))
+	l := New(strings.NewReader(in), "test")
 	tok := l.Lex()
 	if len(tok) == 0 {
 		t.Error("Lexer found error, token empty")
@@ -44,11 +44,19 @@ func TestLexerFloats(t *testing.T) {
 		"1.2e-2",
 		"15e4",
 		"-12",
+		"1e+3",
+		"2.5E-1",
+		"0x1F",
+		"0XfF_fF",
+		"0o17",
+		"-0b1010",
+		".5",
+		"-.5e2",
 	}
 	for _, v := range in {
 		t.Run(v, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, v, "test", nil))
-			l := New(strings.NewReader(v))
+			l := New(strings.NewReader(v), "test")
 			o := l.Lex()
 			if serror.HasErrors() {
 				t.Fatalf("failed to lex float for input '%s'\n", v)
@@ -63,7 +71,7 @@ func TestLexerFloats(t *testing.T) {
 func TestLexerIdent(t *testing.T) {
 	in := `b a abc abcdefghijklmnopqrstuvwxyz`
 	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
-	l := New(strings.NewReader(in))
+	l := New(strings.NewReader(in), "test")
 	to := l.Lex()
 	if serror.HasErrors() {
 		t.Error("Lexer found error, token empty")
@@ -98,7 +106,7 @@ func TestLexerIdent(t *testing.T) {
 func TestLexerOperators(t *testing.T) {
 	in := `+-/*% let () if = or and not ++ fun for > < match # lambda :: module use`
 	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
-	l := New(strings.NewReader(in))
+	l := New(strings.NewReader(in), "test")
 	to := l.Lex()
 	if serror.HasErrors() {
 		t.Error("Lexer found error, token empty")
@@ -142,7 +150,7 @@ func TestLexerOperators(t *testing.T) {
 func TestLexerArithmetic(t *testing.T) {
 	in := `(+ 1 (* 1 (/ 1 (% 1))))`
 	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
-	l := New(strings.NewReader(in))
+	l := New(strings.NewReader(in), "test")
 	to := l.Lex()
 	if serror.HasErrors() {
 		t.Error("Lexer found error, token empty")
@@ -184,7 +192,7 @@ func TestLexerIgnoreCharsAndComments(t *testing.T) {
 	for _, v := range in {
 		t.Run(v, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, v, "test", nil))
-			l := New(strings.NewReader(v))
+			l := New(strings.NewReader(v), "test")
 			toks := []*token.Token{}
 			if l != nil {
 				toks = l.Lex()
@@ -208,7 +216,7 @@ func TestLexerErrorsOnUnknownTokenAndIntegers(t *testing.T) {
 	for _, v := range in {
 		t.Run(v, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, v, "test", nil))
-			l := New(strings.NewReader(v))
+			l := New(strings.NewReader(v), "test")
 			l.Lex()
 			if !serror.HasErrors() {
 				t.Error("Lexer should have found errors")
@@ -220,7 +228,7 @@ func TestLexerErrorsOnUnknownTokenAndIntegers(t *testing.T) {
 func TestLexerBooleans(t *testing.T) {
 	in := "true false"
 	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
-	l := New(strings.NewReader(in))
+	l := New(strings.NewReader(in), "test")
 	tok := l.Lex()
 	if serror.HasErrors() {
 		t.Error("Lexer found error, token empty")
@@ -247,3 +255,329 @@ func TestLexerBooleans(t *testing.T) {
 		}
 	}
 }
+
+func TestLexerDecimals(t *testing.T) {
+	in := []string{
+		"10.0d",
+		"1_000_000d",
+		"0.01d",
+		"-12d",
+		".25d",
+	}
+	for _, v := range in {
+		t.Run(v, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, v, "test", nil))
+			l := New(strings.NewReader(v), "test")
+			o := l.Lex()
+			if serror.HasErrors() {
+				t.Fatalf("failed to lex decimal for input '%s'\n", v)
+			}
+			if o[0].Type != token.DECIMAL {
+				t.Fatalf("'%s' was not lexed as a decimal, got %s", v, token.TOKEN_NAME_MAP[o[0].Type])
+			}
+			if len(o) != 2 {
+				t.Fatalf("'%s' should result in a decimal and EOF, got %d token", v, len(o))
+			}
+		})
+	}
+}
+
+func TestLexerStringEscapes(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp string
+	}{
+		{in: `"\"quoted\""`, exp: `"quoted"`},
+		{in: `"back\\slash"`, exp: `back\slash`},
+		{in: `"new\nline"`, exp: "new\nline"},
+		{in: `"\ttab"`, exp: "\ttab"},
+		{in: `"\'single\'"`, exp: "'single'"},
+		{in: `"\u{41}\u{1F600}"`, exp: "A😀"},
+		{in: "`raw \\n string`", exp: `raw \n string`},
+		{in: "`multi\nline`", exp: "multi\nline"},
+	}
+	for _, v := range tests {
+		t.Run(v.in, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, v.in, "test", nil))
+			l := New(strings.NewReader(v.in), "test")
+			o := l.Lex()
+			if serror.HasErrors() {
+				t.Fatalf("failed to lex string for input '%s'\n", v.in)
+			}
+			if o[0].Type != token.STRING {
+				t.Fatalf("'%s' was not lexed as a string, got %s", v.in, token.TOKEN_NAME_MAP[o[0].Type])
+			}
+			if o[0].Raw != v.exp {
+				t.Errorf("wanted %q, got %q", v.exp, o[0].Raw)
+			}
+		})
+	}
+}
+
+func TestLexerInvalidStringEscapes(t *testing.T) {
+	tests := []struct {
+		in      string
+		raw     string
+		linePos int
+	}{
+		{in: `"a\qb"`, raw: `\q`, linePos: 2},
+		{in: `(println "\u41")`, raw: `\u`, linePos: 10},
+		{in: `"\u{}"`, raw: `\u{}`, linePos: 1},
+		{in: `"ab\u{110000}"`, raw: `\u{110000}`, linePos: 3},
+		{in: `"\u{zz}"`, raw: `\u{zz}`, linePos: 1},
+		{in: `"\u{41"`, raw: `\u{41`, linePos: 1},
+	}
+	for _, v := range tests {
+		t.Run(v.in, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, v.in, "test", nil))
+			l := New(strings.NewReader(v.in), "test")
+			l.Lex()
+			errs := serror.Default().Errors()
+			if len(errs) == 0 {
+				t.Fatalf("Lexer should have found errors for %q", v.in)
+			}
+			if errs[0].Token.Raw != v.raw {
+				t.Errorf("wanted error for %q, got %q", v.raw, errs[0].Token.Raw)
+			}
+			if errs[0].Token.LinePos != v.linePos {
+				t.Errorf("wanted error at %d, got %d", v.linePos, errs[0].Token.LinePos)
+			}
+		})
+	}
+
+	serror.SetDefault(serror.NewFormatter(&core.CONF, `"\q"`, "test", nil))
+	New(strings.NewReader(`"\q"`), "test").Lex()
+	if errs := serror.Default().Errors(); len(errs) == 0 || !strings.HasPrefix(errs[0].Info, `Unknown escape sequence '\q'`) {
+		t.Errorf("wanted the raw escape sequence in the error, got %v", errs)
+	}
+}
+
+func TestLexerTemplateString(t *testing.T) {
+	tests := []struct {
+		in  string
+		exp []int
+	}{
+		{in: `'{a}'`, exp: []int{token.TEMPLATE_STRING, token.IDENT, token.TEMPLATE_STRING, token.EOF}},
+		{in: `'{{a}}'`, exp: []int{token.TEMPLATE_STRING, token.STRING, token.TEMPLATE_STRING, token.EOF}},
+		{
+			in: `'{(+ a 1)} items'`,
+			exp: []int{
+				token.TEMPLATE_STRING,
+				token.LEFT_BRACE, token.ADD, token.IDENT, token.FLOAT, token.RIGHT_BRACE,
+				token.STRING,
+				token.TEMPLATE_STRING,
+				token.EOF,
+			},
+		},
+		{
+			in: `'{person#["name"]:>8}'`,
+			exp: []int{
+				token.TEMPLATE_STRING,
+				token.IDENT, token.HASHTAG, token.LEFT_BRACKET, token.STRING, token.RIGHT_BRACKET,
+				token.FORMAT,
+				token.TEMPLATE_STRING,
+				token.EOF,
+			},
+		},
+		{in: "'multi\nline'", exp: []int{token.TEMPLATE_STRING, token.STRING, token.TEMPLATE_STRING, token.EOF}},
+	}
+	for _, v := range tests {
+		t.Run(v.in, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, v.in, "test", nil))
+			l := New(strings.NewReader(v.in), "test")
+			o := l.Lex()
+			if serror.HasErrors() {
+				t.Fatalf("failed to lex template string for input '%s'\n", v.in)
+			}
+			if len(o) != len(v.exp) {
+				t.Fatalf("wanted %d token, got %d", len(v.exp), len(o))
+			}
+			for i, toke := range o {
+				if toke.Type != v.exp[i] {
+					t.Errorf("given token '%+v' of type '%s' at pos '%d' does not match expected token '%s'", toke, token.TOKEN_NAME_MAP[toke.Type], i, token.TOKEN_NAME_MAP[v.exp[i]])
+				}
+			}
+		})
+	}
+}
+
+func TestLexerTemplateStringErrors(t *testing.T) {
+	in := []string{
+		`'{}'`,
+		`'{a'`,
+		`'a}'`,
+		`'unterminated`,
+	}
+	for _, v := range in {
+		t.Run(v, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, v, "test", nil))
+			l := New(strings.NewReader(v), "test")
+			l.Lex()
+			if !serror.HasErrors() {
+				t.Error("Lexer should have found errors")
+			}
+		})
+	}
+}
+
+func TestLexerBlockComments(t *testing.T) {
+	in := []string{
+		"#||#",
+		"#| comment |#",
+		"#| multi\nline\ncomment |#",
+		"#| outer #| nested |# still a comment |#",
+		"#| ;; line comment inside |#",
+	}
+	for _, v := range in {
+		t.Run(v, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, v, "test", nil))
+			l := New(strings.NewReader(v), "test")
+			toks := l.Lex()
+			if serror.HasErrors() {
+				t.Error("Lexer should have not found errors")
+			}
+			if len(toks) != 1 {
+				t.Errorf("Lexer should have resulted in 1 token, got %d", len(toks))
+			}
+		})
+	}
+}
+
+func TestLexerUnterminatedBlockComment(t *testing.T) {
+	in := "(+ 1 2) #| outer #| nested |#"
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	l := New(strings.NewReader(in), "test")
+	l.Lex()
+	if !serror.HasErrors() {
+		t.Error("Lexer should have found errors")
+	}
+}
+
+func TestLexerDocComments(t *testing.T) {
+	in := ";;; first line\n;;;second line\n;; not a doc comment\n;;;; neither\n(fun a [] 1)"
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	l := New(strings.NewReader(in), "test")
+	toks := l.Lex()
+	if serror.HasErrors() {
+		t.Error("Lexer should have not found errors")
+	}
+	expectedRaw := []string{"first line", "second line"}
+	for i, raw := range expectedRaw {
+		if toks[i].Type != token.DOC_COMMENT {
+			t.Fatalf("expected doc comment at %d, got %s", i, token.TOKEN_NAME_MAP[toks[i].Type])
+		}
+		if toks[i].Raw != raw {
+			t.Errorf("wanted %q, got %q", raw, toks[i].Raw)
+		}
+	}
+	if toks[2].Type != token.LEFT_BRACE {
+		t.Errorf("expected regular comments to be skipped, got %s", token.TOKEN_NAME_MAP[toks[2].Type])
+	}
+}
+
+func TestLexerMalformedNumbers(t *testing.T) {
+	tests := []struct {
+		in      string
+		raw     string
+		linePos int
+	}{
+		{in: "1-2", raw: "1-2", linePos: 0},
+		{in: "(+ 1e 2)", raw: "1e", linePos: 3},
+		{in: "(+ 1e+ 2)", raw: "1e+", linePos: 3},
+		{in: "1__000", raw: "1__000", linePos: 0},
+		{in: "1_", raw: "1_", linePos: 0},
+		{in: "0x", raw: "0x", linePos: 0},
+		{in: "0b102", raw: "0b102", linePos: 0},
+		{in: "0o8", raw: "0o8", linePos: 0},
+		{in: "12abc", raw: "12abc", linePos: 0},
+		{in: "1.2.3", raw: "1.2.3", linePos: 0},
+		{in: "(println -12.5.1)", raw: "-12.5.1", linePos: 9},
+	}
+	for _, v := range tests {
+		t.Run(v.in, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, v.in, "test", nil))
+			l := New(strings.NewReader(v.in), "test")
+			l.Lex()
+			errs := serror.Default().Errors()
+			if len(errs) != 1 {
+				t.Fatalf("Lexer should have found exactly one error for %q, got %d", v.in, len(errs))
+			}
+			if errs[0].Token.Raw != v.raw {
+				t.Errorf("wanted error for %q, got %q", v.raw, errs[0].Token.Raw)
+			}
+			if errs[0].Token.LinePos != v.linePos {
+				t.Errorf("wanted error at %d, got %d", v.linePos, errs[0].Token.LinePos)
+			}
+		})
+	}
+}
+
+func TestLexerSpans(t *testing.T) {
+	in := "(let ä \"ö😀\")\n  (println\n 'a{ä}')"
+	exp := []struct {
+		raw   string
+		start token.Position
+		end   token.Position
+	}{
+		{"(", token.Position{Offset: 0, Line: 0, Column: 0}, token.Position{Offset: 1, Line: 0, Column: 1}},
+		{"let", token.Position{Offset: 1, Line: 0, Column: 1}, token.Position{Offset: 4, Line: 0, Column: 4}},
+		{"ä", token.Position{Offset: 5, Line: 0, Column: 5}, token.Position{Offset: 7, Line: 0, Column: 6}},
+		{"ö😀", token.Position{Offset: 8, Line: 0, Column: 7}, token.Position{Offset: 16, Line: 0, Column: 11}},
+		{")", token.Position{Offset: 16, Line: 0, Column: 11}, token.Position{Offset: 17, Line: 0, Column: 12}},
+		{"(", token.Position{Offset: 20, Line: 1, Column: 2}, token.Position{Offset: 21, Line: 1, Column: 3}},
+		{"println", token.Position{Offset: 21, Line: 1, Column: 3}, token.Position{Offset: 28, Line: 1, Column: 10}},
+		{"", token.Position{Offset: 30, Line: 2, Column: 1}, token.Position{Offset: 31, Line: 2, Column: 2}},
+		{"a", token.Position{Offset: 31, Line: 2, Column: 2}, token.Position{Offset: 32, Line: 2, Column: 3}},
+		{"ä", token.Position{Offset: 33, Line: 2, Column: 4}, token.Position{Offset: 35, Line: 2, Column: 5}},
+		{"", token.Position{Offset: 36, Line: 2, Column: 6}, token.Position{Offset: 37, Line: 2, Column: 7}},
+		{")", token.Position{Offset: 37, Line: 2, Column: 7}, token.Position{Offset: 38, Line: 2, Column: 8}},
+	}
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	l := New(strings.NewReader(in), "test.phia")
+	toks := l.Lex()
+	if serror.HasErrors() {
+		t.Fatalf("failed to lex %q", in)
+	}
+	if len(toks) != len(exp)+1 {
+		t.Fatalf("expected %d token, got %d", len(exp)+1, len(toks))
+	}
+	for i, e := range exp {
+		span := toks[i].Span()
+		if toks[i].Raw != e.raw || span.Start != e.start || span.End != e.end {
+			t.Errorf("wanted %q at %v..%v, got %q at %v..%v", e.raw, e.start, e.end, toks[i].Raw, span.Start, span.End)
+		}
+		if span.File != "test.phia" {
+			t.Errorf("wanted file %q, got %q", "test.phia", span.File)
+		}
+	}
+}
+
+func FuzzLexer(f *testing.F) {
+	seeds := []string{
+		`(println "Hello World!")`,
+		"(+ 1 0x1F 0b1010 -0o17 1e+3 2.5E-1 1_000 0.1d)",
+		"1-2 1e 0x 1__0 12abc",
+		"'{(+ a 1)} items {b:>8.2f} {{escaped}}'",
+		"'{a' '{}' 'a}'",
+		`"\u{1F600} \q \u{110000}"`,
+		"`raw\nstring`",
+		"#| outer #| nested |# |# ;;; doc\n;; comment",
+		"(let person {name: \"anon\"})(println person#[\"name\"])",
+	}
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, in string) {
+		serror.SetDefault(serror.NewFormatter(&core.CONF, in, "fuzz", nil))
+		l := New(strings.NewReader(in), "fuzz")
+		toks := l.Lex()
+		if len(toks) == 0 || toks[len(toks)-1].Type != token.EOF {
+			t.Errorf("expected token stream to end with EOF for %q", in)
+		}
+		for _, tok := range toks {
+			if tok.Pos > tok.End.Offset || tok.End.Offset > len(in) {
+				t.Errorf("invalid span %d..%d for %q in %q", tok.Pos, tok.End.Offset, tok.Raw, in)
+			}
+		}
+	})
+}
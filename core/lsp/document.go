@@ -0,0 +1,525 @@
+package lsp
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/checker"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/parser"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+const (
+	FUNCTION = iota + 1
+	VARIABLE
+	PARAMETER
+	MODULE
+)
+
+// function, variable, parameter or module defined in the source
+type definition struct {
+	kind  int
+	name  string
+	token *token.Token // name of the definition
+	node  types.Node   // definition of the function, variable or module
+	doc   string
+	// source code the definition is visible in, definitions visible in
+	// the whole file have an empty scope
+	scope token.Span
+	// function of a parameter, module of a module member
+	parent *definition
+	// functions and variables defined in a module
+	members []*definition
+}
+
+// usage of a definition
+type reference struct {
+	token *token.Token
+	def   *definition
+}
+
+// an open text document and the results of analysing it
+type document struct {
+	uri    string
+	path   string
+	lines  []string
+	tokens []*token.Token
+	ast    []types.Node
+	errors []*serror.Error
+	// value and stack trace of a panic stopping the analysis that was not
+	// caused by an error in the source, empty if there was none
+	internal string
+	// source map of the files loaded by the document
+	imports *serror.ErrorFormatter
+	// inferred types of variables, nil if the document contains errors
+	types map[uint32]types.Type
+	defs  []*definition
+	refs  []reference
+	// lines of files loaded by the document, keyed by their path
+	sources map[string][]string
+}
+
+// lexes, parses and checks src. Errors are collected instead of displayed.
+func analyze(uri string, src string) (d *document) {
+	d = &document{
+		uri:     uri,
+		path:    uriToPath(uri),
+		lines:   strings.Split(src, "\n"),
+		sources: map[string][]string{},
+	}
+	d.imports = serror.NewFormatter(&core.CONF, src, d.path, io.Discard)
+	serror.SetDefault(d.imports)
+	defer func() {
+		d.recovered(recover())
+		d.errors = serror.Default().Errors()
+	}()
+	d.tokens = lexer.New(strings.NewReader(src), d.path).Lex()
+	if serror.HasErrors() {
+		return d
+	}
+	d.ast = parser.New(d.tokens, d.path).Parse()
+	// statements the parser recovered from are indexed even if the source
+	// contains errors, to keep completion and navigation working while typing
+	newIndexer(d).index(d.ast)
+	if serror.HasErrors() {
+		return d
+	}
+	checker.Check(d.ast)
+	d.types = checker.CheckTypes(d.ast)
+	return d
+}
+
+// records the value r of a panic stopping the analysis. Analysing invalid
+// source must never stop the server, errors found until the panic are
+// reported nonetheless. Panics not caused by an error in the source are bugs
+// and reported as an internal error.
+func (d *document) recovered(r any) {
+	if r == nil {
+		return
+	}
+	if _, ok := r.(*serror.Error); ok {
+		return
+	}
+	d.internal = fmt.Sprintf("%v\n%s", r, debug.Stack())
+}
+
+// variables visible in a function, lambda, loop or module
+type scope struct {
+	vars map[string]*definition
+	span token.Span
+	// variables defined via let are stored in the innermost function
+	// scope, loops only scope their parameters
+	function bool
+}
+
+type indexer struct {
+	d         *document
+	scopes    []*scope
+	functions map[string]*definition
+	modules   map[string]*definition
+	module    *definition // module currently indexed
+	fn        *definition // function currently indexed
+}
+
+func newIndexer(d *document) *indexer {
+	return &indexer{
+		d:         d,
+		scopes:    []*scope{{vars: map[string]*definition{}, function: true}},
+		functions: map[string]*definition{},
+		modules:   map[string]*definition{},
+	}
+}
+
+func (i *indexer) index(ast []types.Node) {
+	// functions may be called and global variables may be used before
+	// their definition
+	for _, n := range ast {
+		switch n := n.(type) {
+		case *expr.Func:
+			i.function(n)
+		case *expr.Var:
+			if !n.IndexAssign {
+				i.variable(n)
+			}
+		}
+	}
+	for _, n := range ast {
+		i.node(n)
+	}
+}
+
+func (i *indexer) define(kind int, ident *expr.Ident, node types.Node, doc string, s *scope) *definition {
+	def := &definition{
+		kind:   kind,
+		name:   ident.Name,
+		token:  ident.Token,
+		node:   node,
+		doc:    doc,
+		scope:  s.span,
+		parent: i.fn,
+	}
+	i.d.defs = append(i.d.defs, def)
+	return def
+}
+
+// defines the function n in the global scope or in the module currently
+// indexed, returns the existing definition if n is already defined
+func (i *indexer) function(n *expr.Func) *definition {
+	ident, ok := n.Name.(*expr.Ident)
+	if !ok {
+		return nil
+	}
+	if i.module != nil {
+		for _, m := range i.module.members {
+			if m.node == n {
+				return m
+			}
+		}
+		def := i.define(FUNCTION, ident, n, n.Doc, i.scopes[0])
+		def.parent = i.module
+		i.module.members = append(i.module.members, def)
+		return def
+	}
+	if def, ok := i.functions[ident.Name]; ok && def.node == n {
+		return def
+	}
+	def := i.define(FUNCTION, ident, n, n.Doc, i.scopes[0])
+	i.functions[ident.Name] = def
+	return def
+}
+
+// defines the variable of n in the innermost function scope, assigning to a
+// variable already defined is a reference to its definition
+func (i *indexer) variable(n *expr.Var) {
+	target := i.scopes[len(i.scopes)-1]
+	for j := len(i.scopes) - 1; !target.function; j-- {
+		target = i.scopes[j]
+	}
+	if def, ok := target.vars[n.Ident.Name]; ok {
+		if def.node != n {
+			i.reference(n.Ident.Token, def)
+		}
+		return
+	}
+	def := i.define(VARIABLE, n.Ident, n, n.Doc, target)
+	if i.module != nil && i.fn == nil {
+		def.parent = i.module
+		i.module.members = append(i.module.members, def)
+	}
+	target.vars[n.Ident.Name] = def
+}
+
+func (i *indexer) lookup(name string) *definition {
+	for j := len(i.scopes) - 1; j >= 0; j-- {
+		if def, ok := i.scopes[j].vars[name]; ok {
+			return def
+		}
+	}
+	return nil
+}
+
+func (i *indexer) reference(t *token.Token, def *definition) {
+	i.d.refs = append(i.d.refs, reference{token: t, def: def})
+}
+
+func (i *indexer) push(span token.Span, function bool) *scope {
+	s := &scope{vars: map[string]*definition{}, span: span, function: function}
+	i.scopes = append(i.scopes, s)
+	return s
+}
+
+func (i *indexer) pop() {
+	i.scopes = i.scopes[:len(i.scopes)-1]
+}
+
+// indexes params and body in a new scope spanning span
+func (i *indexer) scoped(span token.Span, function bool, params *expr.Array, body []types.Node) {
+	s := i.push(span, function)
+	if params != nil {
+		for _, p := range params.Children {
+			if ident, ok := p.(*expr.Ident); ok {
+				s.vars[ident.Name] = i.define(PARAMETER, ident, p, "", s)
+			}
+		}
+	}
+	for _, n := range body {
+		i.node(n)
+	}
+	i.pop()
+}
+
+func (i *indexer) node(n types.Node) {
+	switch n := n.(type) {
+	case nil:
+		return
+	case *expr.Ident:
+		if def := i.lookup(n.Name); def != nil {
+			i.reference(n.Token, def)
+		}
+	case *expr.Var:
+		for _, v := range n.Value {
+			i.node(v)
+		}
+		// the target of index assignments is referenced by the index
+		if !n.IndexAssign {
+			i.variable(n)
+		}
+	case *expr.Func:
+		def := i.function(n)
+		fn := i.fn
+		i.fn = def
+		i.scoped(n.Span, true, n.Params, n.Body)
+		i.fn = fn
+	case *expr.Lambda:
+		i.scoped(n.Span, true, n.Params, n.Body)
+	case *expr.For:
+		i.node(n.LoopOver)
+		i.scoped(n.Span, false, n.Params, n.Body)
+	case *expr.Call:
+		if def, ok := i.functions[n.Token.Raw]; ok {
+			i.reference(n.Token, def)
+		}
+		for _, arg := range n.Args {
+			i.node(arg)
+		}
+	case *expr.Module:
+		def := &definition{
+			kind:  MODULE,
+			name:  n.Name,
+			token: i.d.next(n.Token),
+			node:  n,
+			doc:   n.Doc,
+		}
+		i.d.defs = append(i.d.defs, def)
+		i.modules[n.Name] = def
+		module := i.module
+		i.module = def
+		i.push(n.Span, true)
+		for _, c := range n.Children {
+			if f, ok := c.(*expr.Func); ok {
+				i.function(f)
+			}
+		}
+		for _, c := range n.Children {
+			i.node(c)
+		}
+		i.pop()
+		i.module = module
+	case *expr.Use:
+		if ident, ok := n.Name.(*expr.Ident); ok {
+			if def, ok := i.modules[ident.Name]; ok {
+				i.reference(ident.Token, def)
+			}
+		}
+	default:
+		for _, c := range checker.Children(n) {
+			i.node(c)
+		}
+	}
+}
+
+// returns the token following t, t if t is not a token of the document
+func (d *document) next(t *token.Token) *token.Token {
+	for j, tok := range d.tokens {
+		if tok == t && j+1 < len(d.tokens) {
+			return d.tokens[j+1]
+		}
+	}
+	return t
+}
+
+// returns the index of the token of the document at line and column, the
+// position directly behind a token is considered part of it. Returns -1 if
+// there is no token at the position.
+func (d *document) tokenAt(line, column int) int {
+	for j, t := range d.tokens {
+		if t.Type == token.EOF {
+			break
+		}
+		if t.Line <= line && t.End.Line >= line &&
+			(t.Line < line || t.LinePos <= column) &&
+			(t.End.Line > line || column <= t.End.Column) {
+			// prefer the token starting at the position over the
+			// token ending at it: a|(b. Identifiers and :: are kept, they
+			// are completed: person::|)
+			if j+1 < len(d.tokens) && d.tokens[j+1].Line == line && d.tokens[j+1].LinePos == column && t.Type != token.IDENT && t.Type != token.DOUBLE_COLON {
+				return j + 1
+			}
+			return j
+		}
+	}
+	return -1
+}
+
+// returns the definition of the token at line and column, nil if there is
+// none
+func (d *document) definitionAt(line, column int) *definition {
+	j := d.tokenAt(line, column)
+	if j == -1 {
+		return nil
+	}
+	t := d.tokens[j]
+	for _, def := range d.defs {
+		if def.token == t {
+			return def
+		}
+	}
+	for _, ref := range d.refs {
+		if ref.token == t {
+			return ref.def
+		}
+	}
+	if t.Type != token.IDENT {
+		return nil
+	}
+	// module access, such as person::str, is resolved using the tokens
+	if j+1 < len(d.tokens) && d.tokens[j+1].Type == token.DOUBLE_COLON {
+		return d.global(MODULE, t.Raw)
+	}
+	if module := d.moduleBefore(j); module != nil {
+		for _, m := range module.members {
+			if m.name == t.Raw {
+				return m
+			}
+		}
+		return nil
+	}
+	// statements containing errors are not indexed, fall back to the
+	// global definitions
+	for _, kind := range []int{FUNCTION, MODULE, VARIABLE} {
+		if def := d.global(kind, t.Raw); def != nil {
+			return def
+		}
+	}
+	return nil
+}
+
+// returns the module accessed by the :: in front of the token at index j, nil
+// if the token is not a module member access
+func (d *document) moduleBefore(j int) *definition {
+	if j < 2 || d.tokens[j-1].Type != token.DOUBLE_COLON || d.tokens[j-2].Type != token.IDENT {
+		return nil
+	}
+	return d.global(MODULE, d.tokens[j-2].Raw)
+}
+
+// returns the definition of kind named name visible in the whole file
+func (d *document) global(kind int, name string) *definition {
+	for _, def := range d.defs {
+		if def.kind == kind && def.name == name && def.scope == (token.Span{}) && (kind == MODULE || def.parent == nil) {
+			return def
+		}
+	}
+	return nil
+}
+
+// type of the value of the variable or parameter def
+func (d *document) typeOf(def *definition) types.Type {
+	ident, ok := def.node.(*expr.Ident)
+	if v, isVar := def.node.(*expr.Var); isVar {
+		ident, ok = v.Ident, true
+	}
+	if !ok {
+		return types.ANY
+	}
+	if ident.Type != nil {
+		return types.Type(ident.Type.Raw)
+	}
+	if t, ok := d.types[ident.Key]; ok && def.kind == VARIABLE {
+		return t
+	}
+	return types.ANY
+}
+
+// converts the position p in the file to a position in the protocol, files
+// other than the document are read to compute the position
+func (d *document) position(file string, p token.Position) Position {
+	lines := d.lines
+	if file != "" && file != d.path {
+		var ok bool
+		if lines, ok = d.sources[file]; !ok {
+			content, _ := os.ReadFile(file)
+			lines = strings.Split(string(content), "\n")
+			d.sources[file] = lines
+		}
+	}
+	if p.Line >= len(lines) {
+		return Position{Line: p.Line, Character: p.Column}
+	}
+	runes := []rune(lines[p.Line])
+	return Position{
+		Line:      p.Line,
+		Character: len(utf16.Encode(runes[:min(p.Column, len(runes))])),
+	}
+}
+
+func (d *document) span(s token.Span) Range {
+	return Range{
+		Start: d.position(s.File, s.Start),
+		End:   d.position(s.File, s.End),
+	}
+}
+
+// converts the utf-16 character offset of p to a column in runes
+func (d *document) column(p Position) int {
+	if p.Line >= len(d.lines) {
+		return p.Character
+	}
+	units := 0
+	for column, r := range []rune(d.lines[p.Line]) {
+		if units >= p.Character {
+			return column
+		}
+		// runes outside of the basic multilingual plane are encoded as
+		// surrogate pairs
+		units++
+		if r > 0xFFFF {
+			units++
+		}
+	}
+	return len([]rune(d.lines[p.Line]))
+}
+
+// returns the location of the source code spanned by s
+func (d *document) location(s token.Span) Location {
+	uri := d.uri
+	if s.File != "" && s.File != d.path {
+		uri = pathToURI(s.File)
+	}
+	return Location{URI: uri, Range: d.span(s)}
+}
+
+// range covering the whole document
+func (d *document) whole() Range {
+	last := len(d.lines) - 1
+	return Range{End: Position{
+		Line:      last,
+		Character: len(utf16.Encode([]rune(d.lines[last]))),
+	}}
+}
+
+func uriToPath(uri string) string {
+	u, err := url.Parse(uri)
+	if err != nil || u.Scheme != "file" {
+		return uri
+	}
+	return filepath.FromSlash(u.Path)
+}
+
+func pathToURI(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(path)}).String()
+}
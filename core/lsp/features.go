@@ -0,0 +1,202 @@
+package lsp
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/formatter"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+)
+
+// converts the errors found while analysing the document to diagnostics,
+// errors in loaded files are reported at the path of the load statement
+func (d *document) diagnostics() []Diagnostic {
+	diagnostics := make([]Diagnostic, 0, len(d.errors))
+	for _, err := range d.errors {
+		diagnostic := Diagnostic{
+			Severity: SEVERITY_ERROR,
+			Code:     err.Code,
+			Source:   "sophia",
+			Message:  err.Title + ": " + err.Info,
+		}
+		switch err.Severity {
+		case serror.WARNING:
+			diagnostic.Severity = SEVERITY_WARNING
+		case serror.NOTE:
+			diagnostic.Severity = SEVERITY_INFORMATION
+		}
+		if err.Help != "" {
+			diagnostic.Message += "\n" + err.Help
+		}
+		for _, l := range err.Labels {
+			if l.Span.File == "" || l.Span.File == d.path {
+				diagnostic.RelatedInformation = append(diagnostic.RelatedInformation, DiagnosticRelatedInformation{
+					Location: d.location(l.Span),
+					Message:  l.Message,
+				})
+			}
+		}
+		if err.Span.File == "" || err.Span.File == d.path {
+			diagnostic.Range = d.span(err.Span)
+		} else {
+			diagnostic.Range = d.span(d.loadOf(err.Span.File))
+			diagnostic.Message = fmt.Sprintf("%s:%d:%d: %s", err.Span.File, err.Span.Start.Line+1, err.Span.Start.Column+1, diagnostic.Message)
+		}
+		diagnostics = append(diagnostics, diagnostic)
+	}
+	if d.internal != "" {
+		message, _, _ := strings.Cut(d.internal, "\n")
+		diagnostics = append(diagnostics, Diagnostic{
+			Severity: SEVERITY_ERROR,
+			Source:   "sophia",
+			Message:  "Internal error: analysing the document failed: " + message + ", see the log of the language server",
+		})
+	}
+	return diagnostics
+}
+
+// returns the span of the path of the load statement in the document
+// loading file, directly or via other loaded files
+func (d *document) loadOf(file string) token.Span {
+	chain := d.imports.ImportedFrom(file)
+	if len(chain) == 0 {
+		return token.Span{}
+	}
+	return chain[len(chain)-1].Span()
+}
+
+func hover(d *document, line, column int) any {
+	j := d.tokenAt(line, column)
+	if j == -1 {
+		return nil
+	}
+	t := d.tokens[j]
+	b := strings.Builder{}
+	if def := d.definitionAt(line, column); def != nil {
+		b.WriteString("```sophia\n")
+		b.WriteString(d.signature(def))
+		b.WriteString("\n```")
+		if def.kind == PARAMETER && def.parent != nil {
+			fmt.Fprintf(&b, "\n\nParameter of `%s`", def.parent.name)
+		}
+		if def.doc != "" {
+			b.WriteString("\n\n")
+			b.WriteString(def.doc)
+		}
+	} else if sig, ok := builtin.BUILTINS[t.Raw]; ok && t.Type == token.IDENT {
+		fmt.Fprintf(&b, "```sophia\n%s:%s\n```\n\n%s", sig.Format(t.Raw), sig.Returns, sig.Doc)
+	} else {
+		return nil
+	}
+	r := d.span(t.Span())
+	return Hover{
+		Contents: MarkupContent{Kind: "markdown", Value: b.String()},
+		Range:    &r,
+	}
+}
+
+// formats the definition as written in the source, including its type
+// annotations and inferred types: (fun square:float [n:float])
+func (d *document) signature(def *definition) string {
+	switch def.kind {
+	case FUNCTION:
+		return def.node.(*expr.Func).Signature()
+	case MODULE:
+		return "(module " + def.name + ")"
+	case PARAMETER:
+		return fmt.Sprintf("%s:%s", def.name, d.typeOf(def))
+	default:
+		return fmt.Sprintf("(let %s:%s)", def.name, d.typeOf(def))
+	}
+}
+
+func completion(d *document, line, column int) any {
+	items := make([]CompletionItem, 0)
+	// members of the module in front of the cursor: person::|
+	if j := d.tokenAt(line, column); j != -1 {
+		t := d.tokens[j]
+		var module *definition
+		if t.Type == token.DOUBLE_COLON && j > 0 && d.tokens[j-1].Type == token.IDENT {
+			module = d.global(MODULE, d.tokens[j-1].Raw)
+		} else if t.Type == token.IDENT {
+			module = d.moduleBefore(j)
+		}
+		if module != nil {
+			for _, m := range module.members {
+				items = append(items, d.item(m))
+			}
+			return items
+		}
+	}
+
+	for keyword, ttype := range token.KEYWORD_MAP {
+		if ttype == token.IDENT {
+			continue
+		}
+		items = append(items, CompletionItem{Label: keyword, Kind: COMPLETION_KEYWORD})
+	}
+	for name, f := range builtin.BUILTINS {
+		items = append(items, CompletionItem{
+			Label:         name,
+			Kind:          COMPLETION_FUNCTION,
+			Detail:        f.Format(name),
+			Documentation: &MarkupContent{Kind: "markdown", Value: f.Doc},
+		})
+	}
+	seen := map[string]bool{}
+	for _, def := range d.defs {
+		if def.kind != MODULE && def.parent != nil && def.parent.kind == MODULE {
+			// module members are completed after ::
+			continue
+		}
+		if def.scope != (token.Span{}) && !def.scope.Contains(line, column) {
+			continue
+		}
+		if seen[def.name] {
+			continue
+		}
+		seen[def.name] = true
+		items = append(items, d.item(def))
+	}
+	sort.SliceStable(items, func(i, j int) bool {
+		return items[i].Label < items[j].Label
+	})
+	return items
+}
+
+func (d *document) item(def *definition) CompletionItem {
+	item := CompletionItem{Label: def.name, Detail: d.signature(def)}
+	switch def.kind {
+	case FUNCTION:
+		item.Kind = COMPLETION_FUNCTION
+	case MODULE:
+		item.Kind = COMPLETION_MODULE
+	default:
+		item.Kind = COMPLETION_VARIABLE
+	}
+	if def.doc != "" {
+		item.Documentation = &MarkupContent{Kind: "markdown", Value: def.doc}
+	}
+	return item
+}
+
+// formats the document, returns no edits if the document contains errors
+func (s *Server) format(uri string) []TextEdit {
+	d, ok := s.docs[uri]
+	if !ok {
+		return nil
+	}
+	src := strings.Join(d.lines, "\n")
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, d.path, io.Discard))
+	formatted := formatter.Format(src, d.path)
+	if serror.HasErrors() || formatted == src {
+		return []TextEdit{}
+	}
+	return []TextEdit{{Range: d.whole(), NewText: formatted}}
+}
@@ -0,0 +1,299 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core/serror"
+)
+
+const uri = "file:///tmp/test.phia"
+
+const src = `;;; computes the square of n
+(fun square:float [n:float]
+    (* n n))
+(let a 12)
+(println (square a))
+(module person
+    (fun str [p] p))
+(person::str a)`
+
+// src without the unsupported module access, types are only inferred for
+// sources without errors
+var typed = src[:strings.LastIndexByte(src, '\n')]
+
+// sends the messages to a new server and returns the messages written by the
+// server
+func session(t *testing.T, msgs ...map[string]any) []map[string]any {
+	in := &bytes.Buffer{}
+	for _, m := range msgs {
+		m["jsonrpc"] = "2.0"
+		body, _ := json.Marshal(m)
+		fmt.Fprintf(in, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	}
+	out := &bytes.Buffer{}
+	if err := NewServer(in, out).Serve(); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(out)
+	res := []map[string]any{}
+	for {
+		headers, err := textproto.NewReader(r).ReadMIMEHeader()
+		if err != nil {
+			break
+		}
+		length, _ := strconv.Atoi(headers.Get("Content-Length"))
+		body := make([]byte, length)
+		io.ReadFull(r, body)
+		m := map[string]any{}
+		if err := json.Unmarshal(body, &m); err != nil {
+			t.Fatal(err)
+		}
+		res = append(res, m)
+	}
+	return res
+}
+
+func open(text string) map[string]any {
+	return map[string]any{
+		"method": "textDocument/didOpen",
+		"params": map[string]any{"textDocument": map[string]any{"uri": uri, "text": text}},
+	}
+}
+
+func request(id int, method string, line, character int) map[string]any {
+	return map[string]any{
+		"id":     id,
+		"method": method,
+		"params": map[string]any{
+			"textDocument": map[string]any{"uri": uri},
+			"position":     map[string]any{"line": line, "character": character},
+		},
+	}
+}
+
+var shutdown = map[string]any{"id": 99, "method": "shutdown"}
+var exit = map[string]any{"method": "exit"}
+
+// returns the result of the response to the request with the given id
+func result(t *testing.T, msgs []map[string]any, id int) any {
+	for _, m := range msgs {
+		if m["id"] == float64(id) {
+			if m["error"] != nil {
+				t.Fatalf("request %d failed: %v", id, m["error"])
+			}
+			return m["result"]
+		}
+	}
+	t.Fatalf("no response for request %d", id)
+	return nil
+}
+
+func TestServerLifecycle(t *testing.T) {
+	msgs := session(t, map[string]any{"id": 1, "method": "initialize", "params": map[string]any{}}, shutdown, exit)
+	caps := result(t, msgs, 1).(map[string]any)["capabilities"].(map[string]any)
+	for _, c := range []string{"definitionProvider", "hoverProvider", "documentFormattingProvider", "completionProvider"} {
+		if caps[c] == nil {
+			t.Errorf("missing capability %q", c)
+		}
+	}
+	in := strings.NewReader("Content-Length: 33\r\n\r\n{\"jsonrpc\":\"2.0\",\"method\":\"exit\"}")
+	if err := NewServer(in, &bytes.Buffer{}).Serve(); err == nil {
+		t.Errorf("expected an error for exiting without shutdown")
+	}
+}
+
+func TestInvalidMessage(t *testing.T) {
+	in := strings.NewReader("Content-Length: 5\r\n\r\n{bad}")
+	out := &bytes.Buffer{}
+	NewServer(in, out).Serve()
+	if !strings.Contains(out.String(), `"id":null`) || !strings.Contains(out.String(), `"code":-32700`) {
+		t.Errorf("expected a parse error response with a null id, got %q", out.String())
+	}
+}
+
+func TestInternalError(t *testing.T) {
+	d := &document{uri: uri}
+	func() {
+		defer func() { d.recovered(recover()) }()
+		var m map[string]int
+		m["boom"]++
+	}()
+	if !strings.HasPrefix(d.internal, "assignment to entry in nil map\n") {
+		t.Fatalf("expected the panic to be recorded, got %q", d.internal)
+	}
+	diagnostics := d.diagnostics()
+	if len(diagnostics) != 1 || !strings.HasPrefix(diagnostics[0].Message, "Internal error") {
+		t.Errorf("expected an internal error diagnostic, got %v", diagnostics)
+	}
+
+	d = &document{uri: uri}
+	func() {
+		defer func() { d.recovered(recover()) }()
+		panic(&serror.Error{Title: "Type error"})
+	}()
+	if d.internal != "" {
+		t.Errorf("errors in the source are not internal errors, got %q", d.internal)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	tests := []struct {
+		src      string
+		messages []string
+	}{
+		{src: "(let a 1)(println a)", messages: nil},
+		{src: "(println b)", messages: []string{"Undefined variable"}},
+		{src: "(let a:float \"a\")(println a)", messages: []string{"Type error"}},
+		{src: "(println 1", messages: []string{"Unexpected Token"}},
+	}
+	for _, test := range tests {
+		t.Run(test.src, func(t *testing.T) {
+			msgs := session(t, open(test.src), shutdown, exit)
+			params := msgs[0]["params"].(map[string]any)
+			diagnostics := params["diagnostics"].([]any)
+			if len(diagnostics) != len(test.messages) {
+				t.Fatalf("expected %d diagnostics, got %v", len(test.messages), diagnostics)
+			}
+			for i, d := range diagnostics {
+				message := d.(map[string]any)["message"].(string)
+				if !strings.HasPrefix(message, test.messages[i]) {
+					t.Errorf("expected %q, got %q", test.messages[i], message)
+				}
+			}
+		})
+	}
+}
+
+func TestDefinition(t *testing.T) {
+	tests := []struct {
+		name      string
+		line      int
+		character int
+		// expected line and character of the definition, -1 for none
+		defLine      int
+		defCharacter int
+	}{
+		{name: "function call", line: 4, character: 10, defLine: 1, defCharacter: 5},
+		{name: "variable", line: 4, character: 17, defLine: 3, defCharacter: 5},
+		{name: "parameter", line: 2, character: 7, defLine: 1, defCharacter: 19},
+		{name: "module", line: 7, character: 3, defLine: 5, defCharacter: 8},
+		{name: "module member", line: 7, character: 10, defLine: 6, defCharacter: 9},
+		{name: "builtin", line: 4, character: 3, defLine: -1},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			msgs := session(t, open(src), request(1, "textDocument/definition", test.line, test.character), shutdown, exit)
+			res := result(t, msgs, 1)
+			if test.defLine == -1 {
+				if res != nil {
+					t.Fatalf("expected no definition, got %v", res)
+				}
+				return
+			}
+			if res == nil {
+				t.Fatalf("expected a definition")
+			}
+			start := res.(map[string]any)["range"].(map[string]any)["start"].(map[string]any)
+			if start["line"] != float64(test.defLine) || start["character"] != float64(test.defCharacter) {
+				t.Errorf("expected %d:%d, got %v", test.defLine, test.defCharacter, start)
+			}
+		})
+	}
+}
+
+func TestHover(t *testing.T) {
+	tests := []struct {
+		line      int
+		character int
+		contains  []string
+	}{
+		{line: 4, character: 11, contains: []string{"(fun square:float [n:float])", "computes the square of n"}},
+		{line: 3, character: 5, contains: []string{"(let a:float)"}},
+		{line: 2, character: 8, contains: []string{"n:float", "Parameter of `square`"}},
+		{line: 4, character: 2, contains: []string{"(println values...)", "stdout"}},
+	}
+	for _, test := range tests {
+		t.Run(strings.Join(test.contains, ","), func(t *testing.T) {
+			msgs := session(t, open(typed), request(1, "textDocument/hover", test.line, test.character), shutdown, exit)
+			res, ok := result(t, msgs, 1).(map[string]any)
+			if !ok {
+				t.Fatalf("expected hover information")
+			}
+			value := res["contents"].(map[string]any)["value"].(string)
+			for _, c := range test.contains {
+				if !strings.Contains(value, c) {
+					t.Errorf("expected %q in %q", c, value)
+				}
+			}
+		})
+	}
+}
+
+func labels(t *testing.T, res any) map[string]bool {
+	items, ok := res.([]any)
+	if !ok {
+		t.Fatalf("expected completion items, got %v", res)
+	}
+	l := map[string]bool{}
+	for _, item := range items {
+		l[item.(map[string]any)["label"].(string)] = true
+	}
+	return l
+}
+
+func TestCompletion(t *testing.T) {
+	msgs := session(t,
+		open(src),
+		request(1, "textDocument/completion", 2, 5),
+		request(2, "textDocument/completion", 4, 0),
+		request(3, "textDocument/completion", 7, 9),
+		shutdown, exit)
+
+	inFunction := labels(t, result(t, msgs, 1))
+	for _, l := range []string{"println", "map", "let", "square", "a", "n", "person"} {
+		if !inFunction[l] {
+			t.Errorf("expected %q to be completed inside of the function", l)
+		}
+	}
+	if global := labels(t, result(t, msgs, 2)); global["n"] || global["str"] {
+		t.Errorf("parameters and module members must not be completed outside of their scope")
+	}
+	if members := labels(t, result(t, msgs, 3)); len(members) != 1 || !members["str"] {
+		t.Errorf("expected the members of person, got %v", members)
+	}
+
+	// members are completed directly after ::, followed by a closing brace
+	// or the end of the input
+	for _, access := range []string{"(person::)", "(person::"} {
+		msgs := session(t, open(typed+"\n"+access), request(1, "textDocument/completion", 7, 9), shutdown, exit)
+		if members := labels(t, result(t, msgs, 1)); len(members) != 1 || !members["str"] {
+			t.Errorf("expected the members of person for %q, got %v", access, members)
+		}
+	}
+}
+
+func TestFormatting(t *testing.T) {
+	msgs := session(t,
+		open("(let a   1)\n(println a)"),
+		map[string]any{
+			"id":     1,
+			"method": "textDocument/formatting",
+			"params": map[string]any{"textDocument": map[string]any{"uri": uri}},
+		},
+		shutdown, exit)
+	edits := result(t, msgs, 1).([]any)
+	if len(edits) != 1 {
+		t.Fatalf("expected a single edit, got %v", edits)
+	}
+	if text := edits[0].(map[string]any)["newText"]; text != "(let a 1)\n(println a)\n" {
+		t.Errorf("unexpected formatting result %q", text)
+	}
+}
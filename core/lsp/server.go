@@ -0,0 +1,231 @@
+// Implements a language server for sophia, speaking the language server
+// protocol over stdio. The server reuses the lexer, parser, checker and
+// formatter to provide diagnostics, go to definition, hover information,
+// completion and document formatting for .phia files.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/textproto"
+	"strconv"
+	"strings"
+)
+
+type Server struct {
+	r    *bufio.Reader
+	w    io.Writer
+	docs map[string]*document
+	// set once the client requested the shutdown of the server
+	shutdown bool
+}
+
+func NewServer(r io.Reader, w io.Writer) *Server {
+	return &Server{
+		r:    bufio.NewReader(r),
+		w:    w,
+		docs: map[string]*document{},
+	}
+}
+
+// Serve handles messages until the client sends the exit notification or
+// closes the input, returns an error if the client exits without requesting
+// a shutdown first
+func (s *Server) Serve() error {
+	for {
+		msg, err := s.read()
+		if err == io.EOF {
+			if s.shutdown {
+				return nil
+			}
+			return io.ErrUnexpectedEOF
+		} else if err != nil {
+			return err
+		}
+		if msg.Method == "exit" {
+			if s.shutdown {
+				return nil
+			}
+			return errors.New("lsp: exit without shutdown request")
+		}
+		if err := s.handle(msg); err != nil {
+			return err
+		}
+	}
+}
+
+// reads a message prefixed by its headers, the Content-Length header is
+// required
+func (s *Server) read() (*message, error) {
+	headers, err := textproto.NewReader(s.r).ReadMIMEHeader()
+	if err != nil {
+		if err == io.EOF || len(headers) == 0 {
+			return nil, io.EOF
+		}
+		return nil, err
+	}
+	length, err := strconv.Atoi(headers.Get("Content-Length"))
+	if err != nil {
+		return nil, fmt.Errorf("lsp: invalid Content-Length header: %w", err)
+	}
+	body := make([]byte, length)
+	if _, err := io.ReadFull(s.r, body); err != nil {
+		return nil, err
+	}
+	msg := &message{}
+	if err := json.Unmarshal(body, msg); err != nil {
+		return &message{Method: "$/invalid"}, nil
+	}
+	return msg, nil
+}
+
+func (s *Server) write(msg *message) error {
+	msg.JSONRPC = "2.0"
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(s.w, "Content-Length: %d\r\n\r\n%s", len(body), body)
+	return err
+}
+
+// responds to the request with the given id
+func (s *Server) respond(id *json.RawMessage, result any) error {
+	raw, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	r := json.RawMessage(raw)
+	return s.write(&message{ID: id, Result: &r})
+}
+
+// responds to the request with the given id with an error, id is nil if the
+// request could not be read
+func (s *Server) fail(id *json.RawMessage, code int, format string, args ...any) error {
+	if id == nil {
+		// error responses require an id, null if it is unknown
+		null := json.RawMessage("null")
+		id = &null
+	}
+	return s.write(&message{ID: id, Error: &responseError{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}})
+}
+
+func (s *Server) notify(method string, params any) error {
+	raw, err := json.Marshal(params)
+	if err != nil {
+		return err
+	}
+	return s.write(&message{Method: method, Params: raw})
+}
+
+func (s *Server) handle(msg *message) error {
+	var result any
+	var err error
+	switch msg.Method {
+	case "$/invalid":
+		return s.fail(nil, PARSE_ERROR, "invalid message")
+	case "initialize":
+		result = map[string]any{
+			"capabilities": map[string]any{
+				// the full document is sent on each change
+				"textDocumentSync":           1,
+				"definitionProvider":         true,
+				"hoverProvider":              true,
+				"documentFormattingProvider": true,
+				"completionProvider": map[string]any{
+					"triggerCharacters": []string{":", "("},
+				},
+			},
+			"serverInfo": map[string]any{"name": "sophia"},
+		}
+	case "shutdown":
+		s.shutdown = true
+	case "textDocument/didOpen":
+		params := didOpenParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			return s.update(params.TextDocument.URI, params.TextDocument.Text)
+		}
+	case "textDocument/didChange":
+		params := didChangeParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil && len(params.ContentChanges) != 0 {
+			return s.update(params.TextDocument.URI, params.ContentChanges[len(params.ContentChanges)-1].Text)
+		}
+	case "textDocument/didClose":
+		params := documentParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			delete(s.docs, params.TextDocument.URI)
+			return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+				URI:         params.TextDocument.URI,
+				Diagnostics: []Diagnostic{},
+			})
+		}
+	case "textDocument/definition":
+		result, err = s.positional(msg, func(d *document, line, column int) any {
+			if def := d.definitionAt(line, column); def != nil {
+				return d.location(def.token.Span())
+			}
+			return nil
+		})
+	case "textDocument/hover":
+		result, err = s.positional(msg, hover)
+	case "textDocument/completion":
+		result, err = s.positional(msg, completion)
+	case "textDocument/formatting":
+		params := documentParams{}
+		if err = json.Unmarshal(msg.Params, &params); err == nil {
+			result = s.format(params.TextDocument.URI)
+		}
+	default:
+		if msg.ID == nil || strings.HasPrefix(msg.Method, "$/") {
+			// notifications not supported by the server are ignored
+			return nil
+		}
+		return s.fail(msg.ID, METHOD_NOT_FOUND, "method %q not supported", msg.Method)
+	}
+	if msg.ID == nil {
+		return nil
+	}
+	if err != nil {
+		return s.fail(msg.ID, INVALID_PARAMS, "invalid params: %s", err)
+	}
+	return s.respond(msg.ID, result)
+}
+
+// analyses the document and publishes the errors found in it
+func (s *Server) update(uri string, text string) error {
+	d := analyze(uri, text)
+	s.docs[uri] = d
+	if d.internal != "" {
+		err := s.notify("window/logMessage", logMessageParams{
+			Type:    MESSAGE_ERROR,
+			Message: fmt.Sprintf("internal error analysing %s: %s", uri, d.internal),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return s.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: d.diagnostics(),
+	})
+}
+
+// calls f with the document and the position of the request, f is not called
+// for documents not opened
+func (s *Server) positional(msg *message, f func(d *document, line, column int) any) (any, error) {
+	params := textDocumentPositionParams{}
+	if err := json.Unmarshal(msg.Params, &params); err != nil {
+		return nil, err
+	}
+	d, ok := s.docs[params.TextDocument.URI]
+	if !ok {
+		return nil, nil
+	}
+	return f(d, params.Position.Line, d.column(params.Position)), nil
+}
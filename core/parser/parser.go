@@ -1,14 +1,20 @@
 package parser
 
 import (
+	"bytes"
+	"math/big"
 	"os"
+	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 
+	"github.com/xnacly/sophia/core"
 	"github.com/xnacly/sophia/core/alloc"
 	"github.com/xnacly/sophia/core/expr"
 	"github.com/xnacly/sophia/core/lexer"
 	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/shared"
 	"github.com/xnacly/sophia/core/token"
 	"github.com/xnacly/sophia/core/types"
 )
@@ -17,6 +23,21 @@ type Parser struct {
 	token    []*token.Token
 	filename string
 	pos      int
+	// doc comments, mapped to the token following them, removed once
+	// attached to a definition
+	docs map[*token.Token]docComment
+	// set while parsing object keys, which are followed by a colon that is
+	// not a type annotation
+	objectKey bool
+	// parser of the file loading this file, nil for the file executed
+	parent *Parser
+}
+
+// consecutive ;;; comments
+type docComment struct {
+	// first line of the comment
+	token *token.Token
+	text  string
 }
 
 func New(tokens []*token.Token, filename string) *Parser {
@@ -24,56 +45,174 @@ func New(tokens []*token.Token, filename string) *Parser {
 		serror.Add(&token.Token{LinePos: 0, Raw: " "}, "Unexpected end of input", "Source possibly empty")
 		return &Parser{}
 	}
-	return &Parser{
-		token:    tokens,
+	p := &Parser{
+		token:    make([]*token.Token, 0, len(tokens)),
 		pos:      0,
 		filename: filename,
+		docs:     map[*token.Token]docComment{},
+	}
+	// doc comments are removed from the token stream and attached to the
+	// definition following them
+	doc := make([]*token.Token, 0)
+	for _, t := range tokens {
+		if t.Type == token.COMMENT {
+			continue
+		} else if t.Type == token.DOC_COMMENT {
+			doc = append(doc, t)
+			continue
+		}
+		if len(doc) != 0 {
+			lines := make([]string, len(doc))
+			for i, d := range doc {
+				lines[i] = d.Raw
+			}
+			p.docs[t] = docComment{token: doc[0], text: strings.Join(lines, "\n")}
+			doc = doc[:0]
+		}
+		p.token = append(p.token, t)
 	}
+	return p
 }
 
 func (p *Parser) Parse() []types.Node {
 	res := make([]types.Node, 0)
 	for !p.peekIs(token.EOF) {
+		start := p.pos
 		stmt := p.parseStatment()
-		if stmt != nil && stmt.GetToken().Type == token.LOAD {
+		if stmt == nil {
+			// panic mode recovery: skip the erroneous statement and
+			// resynchronize at the next top level statement to report all
+			// errors in a single pass
+			p.skipStatement(start)
+			p.skipStray()
+			continue
+		}
+		if stmt.GetToken().Type == token.LOAD {
 			if loadStmt, ok := stmt.(*expr.Load); ok {
 				res = append(res, p.loadNewSource(loadStmt)...)
 			}
 			continue
 		}
-		if stmt == nil {
-			return res
-		}
 		res = append(res, stmt)
 	}
+	p.unattachedDocs()
 	return res
 }
 
+// skips the tokens up to the next statement, reports them once if there are
+// any, such as `] ] 12` between two statements
+func (p *Parser) skipStray() {
+	if p.peekIs(token.EOF) || p.peekIs(token.LEFT_BRACE) {
+		return
+	}
+	first, skipped := p.peek(), 0
+	for !p.peekIs(token.EOF) && !p.peekIs(token.LEFT_BRACE) {
+		p.advance()
+		skipped++
+	}
+	serror.Add(first, "Unexpected Token", "Missing statement start: Expected Token '(' got '%s', skipped %d token(s) up to the next statement.", token.TOKEN_NAME_MAP[first.Type], skipped)
+}
+
+// returns the doc comment preceding t and marks it as attached
+func (p *Parser) doc(t *token.Token) string {
+	d := p.docs[t]
+	delete(p.docs, t)
+	return d.text
+}
+
+// warns about doc comments not followed by a fun, module or let definition,
+// these are not attached to anything
+func (p *Parser) unattachedDocs() {
+	docs := make([]docComment, 0, len(p.docs))
+	for _, d := range p.docs {
+		docs = append(docs, d)
+	}
+	sort.Slice(docs, func(i, j int) bool {
+		return docs[i].token.Pos < docs[j].token.Pos
+	})
+	for _, d := range docs {
+		serror.Add(d.token, "Unattached doc comment", "Doc comments document the fun, module or let definition directly following them, use ;; for other comments")
+	}
+}
+
+// moves the parser behind the statement starting at start by skipping tokens
+// until all opened braces are closed
+func (p *Parser) skipStatement(start int) {
+	p.pos = start
+	depth := 0
+	for !p.peekIs(token.EOF) {
+		switch p.peek().Type {
+		case token.LEFT_BRACE:
+			depth++
+		case token.RIGHT_BRACE:
+			depth--
+		}
+		p.advance()
+		if depth <= 0 {
+			return
+		}
+	}
+}
+
 func (p *Parser) loadNewSource(node *expr.Load) []types.Node {
 	res := make([]types.Node, 0)
 	for i := 0; i < len(node.Imports); i++ {
 		name := node.Imports[i].GetToken()
-		file, err := os.Open(name.Raw)
-		defer file.Close()
-		if err != nil {
-			serror.Add(name, "Failed to source import", "Couldn't open %q: %q.", name.Raw, err)
+		path := p.resolve(name.Raw)
+		if p.loading(path) {
+			serror.Add(name, "Detected recursion in file imports", "Got %q while already parsing %q.", path, p.filename)
 			continue
 		}
-		lexer := lexer.New(file)
-		token := lexer.Lex()
-		if name.Raw == p.filename {
-			serror.Add(name, "Detected recursion in file imports", "Got %q while already parsing %q.", name.Raw, p.filename)
+		src, err := os.ReadFile(path)
+		if err != nil {
+			serror.Add(name, "Failed to source import", "Couldn't open %q: %q.", path, err)
 			continue
 		}
-		parser := New(token, name.Raw)
+		// errors in the loaded file are displayed against its own source
+		serror.AddSource(path, string(src), name)
+		token := lexer.New(bytes.NewReader(src), path).Lex()
+		parser := New(token, path)
+		parser.parent = p
 		res = append(res, parser.Parse()...)
 	}
 	return res
 }
 
+// returns the path of the file loaded via path, relative paths are resolved
+// against the directory of the file parsed by p if core.CONF.LoadRelative is
+// set
+func (p *Parser) resolve(path string) string {
+	if !core.CONF.LoadRelative || filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(filepath.Dir(p.filename), path)
+}
+
+// reports whether file is currently being parsed by p or the parsers loading
+// it
+func (p *Parser) loading(file string) bool {
+	file = absPath(file)
+	for ; p != nil; p = p.parent {
+		if absPath(p.filename) == file {
+			return true
+		}
+	}
+	return false
+}
+
+// absPath normalises path so different spellings of the same file compare
+// equal, e.g. a.phia and ./a.phia
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
 func (p *Parser) parseStatment() types.Node {
 	childs := make([]types.Node, 0)
 	var stmt types.Node
+	openBrace := p.peek()
 	p.peekError(token.LEFT_BRACE, "Missing statement start")
 	p.advance()
 
@@ -81,14 +220,20 @@ func (p *Parser) parseStatment() types.Node {
 	op := p.peek()
 	p.advance()
 
+	failed := false
 	for {
 		var child types.Node
 		if p.peekIs(token.EOF) || p.peekIs(token.RIGHT_BRACE) {
 			break
 		} else if p.peekIs(token.LEFT_BRACE) {
+			start := p.pos
 			nStmt := p.parseStatment()
 			if nStmt == nil {
-				return nil
+				// keep parsing the remaining children to collect their
+				// errors, this statement is invalid nonetheless
+				p.skipStatement(start)
+				failed = true
+				continue
 			}
 			childs = append(childs, nStmt)
 			continue
@@ -103,6 +248,15 @@ func (p *Parser) parseStatment() types.Node {
 		p.advance()
 	}
 
+	if failed {
+		p.peekError(token.RIGHT_BRACE, "Missing statement end")
+		p.advance()
+		return nil
+	}
+
+	// the statement spans from its opening to its closing brace
+	span := openBrace.Span().Join(p.peek().Span())
+
 	switch op.Type {
 	case token.RETURN:
 		var child types.Node
@@ -114,11 +268,13 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Return{
 			Token: op,
+			Span:  span,
 			Child: child,
 		}
 	case token.MATCH:
 		stmt = &expr.Match{
 			Token:    op,
+			Span:     span,
 			Branches: childs,
 		}
 	case token.LOAD:
@@ -135,6 +291,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Load{
 			Token:   op,
+			Span:    span,
 			Imports: childs[0:],
 		}
 	case token.FOR:
@@ -145,7 +302,7 @@ func (p *Parser) parseStatment() types.Node {
 
 		param, ok := childs[0].(*expr.Array)
 		if !ok {
-			serror.Add(param.Token, "Type error", "Expected the first argument for loop definition to be an array, got %T.", childs[0])
+			serror.Add(childs[0].GetToken(), "Type error", "Expected the first argument for loop definition to be an array, got %T.", childs[0])
 			return nil
 		}
 		if len(param.Children) != 1 {
@@ -154,6 +311,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.For{
 			Token:    op,
+			Span:     span,
 			Params:   param,
 			LoopOver: childs[1],
 			Body:     childs[2:],
@@ -161,6 +319,7 @@ func (p *Parser) parseStatment() types.Node {
 	case token.IDENT:
 		stmt = &expr.Call{
 			Token: op,
+			Span:  span,
 			Key:   alloc.Default.Functions[op.Raw],
 			Args:  childs,
 		}
@@ -171,6 +330,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Lt{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.GT:
@@ -180,6 +340,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Gt{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.FUNC:
@@ -202,9 +363,11 @@ func (p *Parser) parseStatment() types.Node {
 		ident.Key = alloc.NewFunc(ident.Name)
 		stmt = &expr.Func{
 			Token:  op,
+			Span:   span,
 			Name:   ident,
 			Params: params,
 			Body:   childs[2:],
+			Doc:    p.doc(openBrace),
 		}
 	case token.IF:
 		if len(childs) == 0 {
@@ -214,6 +377,7 @@ func (p *Parser) parseStatment() types.Node {
 		cond := childs[0]
 		stmt = &expr.If{
 			Token:     op,
+			Span:      span,
 			Condition: cond,
 			Body:      childs[1:],
 		}
@@ -230,6 +394,7 @@ func (p *Parser) parseStatment() types.Node {
 				IndexAssign: true,
 				Ident:       ident,
 				Token:       ident.Token,
+				Span:        span,
 				Value:       []types.Node{v},
 			}
 		case *expr.Ident:
@@ -238,8 +403,10 @@ func (p *Parser) parseStatment() types.Node {
 			}
 			stmt = &expr.Var{
 				Token: op,
+				Span:  span,
 				Ident: v,
 				Value: childs[1:],
+				Doc:   p.doc(openBrace),
 			}
 		default:
 			serror.Add(childs[0].GetToken(), "Parameter error", "Expected identifier, got %T.", childs[0])
@@ -252,6 +419,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Merge{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.EQUAL:
@@ -261,6 +429,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Equal{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.NEG:
@@ -270,6 +439,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Neg{
 			Token:    op,
+			Span:     span,
 			Children: childs[0],
 		}
 	case token.OR:
@@ -279,6 +449,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Or{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.AND:
@@ -288,6 +459,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.And{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.ADD:
@@ -297,6 +469,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Add{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.SUB:
@@ -306,6 +479,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Sub{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.DIV:
@@ -315,6 +489,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Div{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.MUL:
@@ -324,6 +499,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Mul{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.MOD:
@@ -333,6 +509,7 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		stmt = &expr.Mod{
 			Token:    op,
+			Span:     span,
 			Children: childs,
 		}
 	case token.USE:
@@ -342,11 +519,12 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		ident, ok := childs[0].(*expr.Ident)
 		if !ok {
-			serror.Add(childs[0].GetToken(), "Type Error", "Expected identifier as first argument for using a module, got %T", childs[0])
+			serror.Add(childs[0].GetToken(), "Type error", "Expected identifier as first argument for using a module, got %T", childs[0])
 			return nil
 		}
 		stmt = &expr.Use{
 			Token: op,
+			Span:  span,
 			Name:  ident,
 		}
 	case token.MODULE:
@@ -356,13 +534,15 @@ func (p *Parser) parseStatment() types.Node {
 		}
 		ident, ok := childs[0].(*expr.Ident)
 		if !ok {
-			serror.Add(childs[0].GetToken(), "Type Error", "Expected identifier as first argument for module defintition, got %T", childs[0])
+			serror.Add(childs[0].GetToken(), "Type error", "Expected identifier as first argument for module defintition, got %T", childs[0])
 			return nil
 		}
 		stmt = &expr.Module{
 			Token:    op,
+			Span:     span,
 			Name:     ident.Name,
 			Children: childs[1:],
+			Doc:      p.doc(openBrace),
 		}
 	case token.LAMBDA:
 		if len(childs) < 1 {
@@ -372,11 +552,12 @@ func (p *Parser) parseStatment() types.Node {
 		params, ok := childs[0].(*expr.Array)
 		if !ok {
 			t := childs[0].GetToken()
-			serror.Add(t, "Type error", "Expected the first argument for function definition to be parameters, got %T.", childs[1])
+			serror.Add(t, "Type error", "Expected the first argument for function definition to be parameters, got %T.", childs[0])
 			return nil
 		}
 		stmt = &expr.Lambda{
 			Token:  op,
+			Span:   span,
 			Params: params,
 			Body:   childs[1:],
 		}
@@ -392,7 +573,7 @@ func (p *Parser) parseConstants() types.Node {
 	var child types.Node
 	if p.peekIs(token.FLOAT) {
 		t := p.peek()
-		value, err := strconv.ParseFloat(t.Raw, 64)
+		value, err := parseNumber(t.Raw)
 		if err != nil {
 			serror.Add(t, "Failed to parse number", "%q not a valid floating point integer", t.Raw)
 			value = 0
@@ -401,6 +582,17 @@ func (p *Parser) parseConstants() types.Node {
 			Token: t,
 			Value: value,
 		}
+	} else if p.peekIs(token.DECIMAL) {
+		t := p.peek()
+		value, ok := types.NewDecimal(t.Raw)
+		if !ok {
+			serror.Add(t, "Failed to parse number", "%q not a valid decimal", t.Raw)
+			value, _ = types.NewDecimal("0")
+		}
+		child = &expr.Decimal{
+			Token: t,
+			Value: value,
+		}
 	} else if p.peekIs(token.IDENT) {
 		tok := p.peek()
 		ident := &expr.Ident{
@@ -412,6 +604,9 @@ func (p *Parser) parseConstants() types.Node {
 		} else {
 			ident.Key = val
 		}
+		if p.peekNext().Type == token.COLON && !p.objectKey {
+			ident.Type = p.parseTypeAnnotation()
+		}
 
 		child = ident
 	} else if p.peekIs(token.STRING) {
@@ -429,10 +624,46 @@ func (p *Parser) parseConstants() types.Node {
 	return child
 }
 
+// parses the type annotation following the identifier at the current position,
+// such as float in a:float, the parser is positioned at the type afterwards
+func (p *Parser) parseTypeAnnotation() *token.Token {
+	p.advance() // skip ident
+	p.advance() // skip :
+	t := p.peek()
+	if t.Type != token.IDENT {
+		serror.Add(t, "Unexpected Token", "Missing type annotation: Expected a type after ':' got '%s'.", token.TOKEN_NAME_MAP[t.Type])
+		return nil
+	}
+	if !types.KNOWN_TYPES[types.Type(t.Raw)] {
+		known := make([]string, 0, len(types.KNOWN_TYPES))
+		for k := range types.KNOWN_TYPES {
+			known = append(known, string(k))
+		}
+		serror.Add(t, "Unknown type", "%q is not a type, expected any of any, float, decimal, string, bool, array or object.", t.Raw).Suggest(t.Raw, known)
+	}
+	return t
+}
+
+// parses decimal floating point numbers and integers prefixed with 0x, 0o
+// and 0b
+func parseNumber(raw string) (float64, error) {
+	digits := strings.TrimPrefix(raw, "-")
+	if len(digits) > 1 && digits[0] == '0' && strings.ContainsRune("xXoObB", rune(digits[1])) {
+		i, ok := new(big.Int).SetString(raw, 0)
+		if !ok {
+			return 0, strconv.ErrSyntax
+		}
+		f, _ := new(big.Float).SetInt(i).Float64()
+		return f, nil
+	}
+	return strconv.ParseFloat(raw, 64)
+}
+
 func (p *Parser) parseArguments() types.Node {
 	var child types.Node
 	p.peekErrorMany("Missing or unknown argument",
 		token.FLOAT,
+		token.DECIMAL,
 		token.STRING,
 		token.IDENT,
 		token.BOOL,
@@ -451,6 +682,7 @@ func (p *Parser) parseArguments() types.Node {
 			p.advance()
 		}
 		p.peekError(token.RIGHT_BRACKET, "Missing statement end")
+		param.Span = param.Token.Span().Join(p.peek().Span())
 		child = param
 	} else if p.peekNext().Type == token.HASHTAG {
 		t := &expr.Index{
@@ -469,6 +701,7 @@ func (p *Parser) parseArguments() types.Node {
 				p.advance() // skip ]
 			}
 		}
+		t.Span = t.Token.Span().Join(p.peek().Span())
 
 		child = t
 	} else if p.peekIs(token.LEFT_CURLY) {
@@ -489,9 +722,11 @@ func (p *Parser) parseObject() types.Node {
 	}
 	p.advance()
 	for !p.peekIs(token.RIGHT_CURLY) && !p.peekIs(token.EOF) {
+		p.objectKey = true
 		op := expr.ObjectPair{
 			Key: p.parseArguments(),
 		}
+		p.objectKey = false
 		p.advance() // skip key
 		if p.peek().Type != token.COLON {
 			p.peekError(token.COLON, "missing object key value divider")
@@ -503,6 +738,7 @@ func (p *Parser) parseObject() types.Node {
 		o.Children = append(o.Children, op)
 	}
 	p.peekError(token.RIGHT_CURLY, "missing object end")
+	o.Span = o.Token.Span().Join(p.peek().Span())
 	return &o
 }
 
@@ -512,13 +748,43 @@ func (p *Parser) parseTemplateString() *expr.TemplateString {
 		Children: make([]types.Node, 0),
 	}
 	p.advance()
-	for !p.peekIs(token.TEMPLATE_STRING) {
-		t.Children = append(t.Children, p.parseArguments())
-		p.advance()
+	for !p.peekIs(token.TEMPLATE_STRING) && !p.peekIs(token.EOF) {
+		var child types.Node
+		if p.peekIs(token.LEFT_BRACE) {
+			child = p.parseStatment()
+			if child == nil {
+				return t
+			}
+		} else {
+			child = p.parseArguments()
+			p.advance()
+		}
+		if p.peekIs(token.FORMAT) {
+			child = p.parseFormat(child)
+		}
+		t.Children = append(t.Children, child)
 	}
+	t.Span = t.Token.Span().Join(p.peek().Span())
 	return t
 }
 
+// wraps child into a format node using the format specifier at the current
+// position
+func (p *Parser) parseFormat(child types.Node) types.Node {
+	tok := p.peek()
+	p.advance() // skip format specifier
+	spec, err := shared.ParseFormatSpec(tok.Raw)
+	if err != nil {
+		serror.Add(tok, "Invalid format specifier", "%q is not a valid format specifier: %s.", tok.Raw, err)
+		return child
+	}
+	return &expr.Format{
+		Token: tok,
+		Child: child,
+		Spec:  spec,
+	}
+}
+
 func (p *Parser) advance() {
 	if p.peek().Type == token.EOF {
 		return
@@ -1,19 +1,24 @@
 package parser
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 
 	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/expr"
 	"github.com/xnacly/sophia/core/lexer"
 	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/types"
 )
 
 func TestParserHelloWorld(t *testing.T) {
 	in := `(println "Hello World!")`
 
 	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
-	l := lexer.New(strings.NewReader(in))
+	l := lexer.New(strings.NewReader(in), "test")
 	token := l.Lex()
 
 	New(token, "test")
@@ -37,7 +42,7 @@ func TestParserErrors(t *testing.T) {
 	for _, s := range in {
 		t.Run(s, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, s, "test", nil))
-			l := lexer.New(strings.NewReader(s))
+			l := lexer.New(strings.NewReader(s), "test")
 			p := New(l.Lex(), "test")
 			p.Parse()
 			if !serror.HasErrors() {
@@ -56,7 +61,7 @@ func TestParserIndex(t *testing.T) {
 	for _, s := range in {
 		t.Run(s, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, s, "test", nil))
-			l := lexer.New(strings.NewReader(s))
+			l := lexer.New(strings.NewReader(s), "test")
 			tokens := l.Lex()
 			p := New(tokens, "test")
 			p.Parse()
@@ -77,7 +82,7 @@ func TestParserArray(t *testing.T) {
 	for _, s := range in {
 		t.Run(s, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, s, "test", nil))
-			l := lexer.New(strings.NewReader(s))
+			l := lexer.New(strings.NewReader(s), "test")
 			tokens := l.Lex()
 			p := New(tokens, "test")
 			p.Parse()
@@ -99,7 +104,7 @@ func TestParserModules(t *testing.T) {
 	for _, s := range in {
 		t.Run(s, func(t *testing.T) {
 			serror.SetDefault(serror.NewFormatter(&core.CONF, s, "test", nil))
-			l := lexer.New(strings.NewReader(s))
+			l := lexer.New(strings.NewReader(s), "test")
 			tokens := l.Lex()
 			p := New(tokens, "test")
 			p.Parse()
@@ -110,3 +115,252 @@ func TestParserModules(t *testing.T) {
 		})
 	}
 }
+
+func TestParserDocComments(t *testing.T) {
+	in := `
+;;; squares n
+;;; returns n*n
+(fun square [n] (* n n))
+;;; a person
+(module person)
+;;; the answer
+(let answer 42)
+(let undocumented 0)`
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	l := lexer.New(strings.NewReader(in), "test")
+	ast := New(l.Lex(), "test").Parse()
+	if serror.HasErrors() {
+		serror.Display()
+		t.Fatalf("parsing should not fail for %q, it did", in)
+	}
+	docs := []string{
+		ast[0].(*expr.Func).Doc,
+		ast[1].(*expr.Module).Doc,
+		ast[2].(*expr.Var).Doc,
+		ast[3].(*expr.Var).Doc,
+	}
+	expected := []string{"squares n\nreturns n*n", "a person", "the answer", ""}
+	for i, doc := range docs {
+		if doc != expected[i] {
+			t.Errorf("wanted doc %q, got %q", expected[i], doc)
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	type diagnostic struct {
+		line  int
+		title string
+	}
+	tests := []struct {
+		name string
+		in   string
+		exp  []diagnostic
+	}{
+		{
+			name: "errors in consecutive statements",
+			in: `(let a 1)
+(fun 12 [a] (+ a 1))
+(println (+ 1) "ok")
+(for x 10 (println x))`,
+			exp: []diagnostic{
+				{1, "Type error"},
+				{2, "Incorrect parameter amount"},
+				{3, "Type error"},
+			},
+		},
+		{
+			name: "multiple errors in one statement",
+			in:   `(println (not 1 2) (< 1) (lambda))`,
+			exp: []diagnostic{
+				{0, "Incorrect parameter amount"},
+				{0, "Incorrect parameter amount"},
+				{0, "Not enough parameters"},
+			},
+		},
+		{
+			name: "nested errors",
+			in: `(fun f [a]
+    (if (> a)
+        (println (* a))))
+(let b (- 5))`,
+			exp: []diagnostic{
+				{1, "Incorrect parameter amount"},
+				{2, "Incorrect parameter amount"},
+				{3, "Incorrect parameter amount"},
+			},
+		},
+		{
+			name: "garbage between statements",
+			in: `(return 1 2)
+] ] 12 "garbage"
+(load 12)`,
+			exp: []diagnostic{
+				{0, "Too many arguments"},
+				{1, "Unexpected Token"},
+				{2, "Type error"},
+			},
+		},
+		{
+			name: "unclosed nested statement",
+			in: `(let x 1)
+(let y (`,
+			exp: []diagnostic{
+				{1, "Unexpected Token"},
+				{1, "Unexpected Token"},
+			},
+		},
+		{
+			name: "valid statements are not reported",
+			in: `(let a 1)
+(+ 1)
+(let b 2)
+(println a b)
+(- 1)`,
+			exp: []diagnostic{
+				{1, "Incorrect parameter amount"},
+				{4, "Incorrect parameter amount"},
+			},
+		},
+		{
+			name: "unattached doc comments",
+			in: `;;; not a definition
+(println 1)
+(fun f []
+    ;;; documents the let
+    (let a 1)
+    ;;; inside a body
+    (println a))
+;;; end of file`,
+			exp: []diagnostic{
+				{0, "Unattached doc comment"},
+				{5, "Unattached doc comment"},
+				{7, "Unattached doc comment"},
+			},
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			serror.SetDefault(serror.NewFormatter(&core.CONF, test.in, "test", nil))
+			l := lexer.New(strings.NewReader(test.in), "test")
+			New(l.Lex(), "test").Parse()
+			errs := serror.Default().Errors()
+			if len(errs) != len(test.exp) {
+				for _, e := range errs {
+					t.Logf("%d: %s: %s", e.Token.Line, e.Title, e.Info)
+				}
+				t.Fatalf("wanted %d errors, got %d", len(test.exp), len(errs))
+			}
+			for i, e := range errs {
+				if e.Token.Line != test.exp[i].line || e.Title != test.exp[i].title {
+					t.Errorf("wanted error %q in line %d, got %q in line %d", test.exp[i].title, test.exp[i].line, e.Title, e.Token.Line)
+				}
+			}
+		})
+	}
+}
+
+func TestParserSpans(t *testing.T) {
+	in := "(let a [1 2])\n(println\n  (+ a#[0] 1)\n  'ö{a}')"
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	l := lexer.New(strings.NewReader(in), "test")
+	ast := New(l.Lex(), "test").Parse()
+	if serror.HasErrors() || len(ast) != 2 {
+		t.Fatalf("failed to parse %q", in)
+	}
+	call := ast[1].(*expr.Call)
+	tests := []struct {
+		node types.Node
+		exp  string
+	}{
+		{ast[0], "(let a [1 2])"},
+		{ast[0].(*expr.Var).Value[0], "[1 2]"},
+		{call, "(println\n  (+ a#[0] 1)\n  'ö{a}')"},
+		{call.Args[0], "(+ a#[0] 1)"},
+		{call.Args[0].(*expr.Add).Children[0], "a#[0]"},
+		{call.Args[1], "'ö{a}'"},
+	}
+	for _, test := range tests {
+		span := test.node.GetSpan()
+		if got := in[span.Start.Offset:span.End.Offset]; got != test.exp {
+			t.Errorf("wanted span of %q, got %q", test.exp, got)
+		}
+	}
+	if span := call.Args[1].GetSpan(); span.Start.Line != 3 || span.End.Column != 8 {
+		t.Errorf("wanted template string to end in line 3 column 8, got %v", span)
+	}
+}
+
+func TestParserTypeAnnotations(t *testing.T) {
+	in := `(fun add:float [a:float b] (+ a b))(let c:decimal 1d)(let o {a: b})`
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	ast := New(lexer.New(strings.NewReader(in), "test").Lex(), "test").Parse()
+	if serror.HasErrors() || len(ast) != 3 {
+		t.Fatalf("failed to parse %q: %v", in, serror.Default().Errors())
+	}
+	f := ast[0].(*expr.Func)
+	params := f.Params.Children
+	if f.Name.(*expr.Ident).Type.Raw != "float" || params[0].(*expr.Ident).Type.Raw != "float" || params[1].(*expr.Ident).Type != nil {
+		t.Errorf("wrong type annotations for function %q", in)
+	}
+	if ast[1].(*expr.Var).Ident.Type.Raw != "decimal" {
+		t.Errorf("wrong type annotation for variable")
+	}
+	if key := ast[2].(*expr.Var).Value[0].(*expr.Object).Children[0].Key.(*expr.Ident); key.Type != nil {
+		t.Errorf("object key parsed as type annotation")
+	}
+
+	for _, in := range []string{"(let a:number 1)", "(let a: 1)"} {
+		serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+		New(lexer.New(strings.NewReader(in), "test").Lex(), "test").Parse()
+		if !serror.HasErrors() {
+			t.Errorf("expected errors for %q", in)
+		}
+	}
+}
+
+func TestParserLoad(t *testing.T) {
+	dir := t.TempDir()
+	a, b := filepath.Join(dir, "a.phia"), filepath.Join(dir, "b.phia")
+	if err := os.WriteFile(a, []byte(fmt.Sprintf("(load %q)\n(let a 1)", b)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte(fmt.Sprintf("(let b 2)\n(load %q)", a)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	in := fmt.Sprintf("(load %q)", a)
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	New(lexer.New(strings.NewReader(in), "test").Lex(), "test").Parse()
+	errs := serror.Default().Errors()
+	if len(errs) != 1 || errs[0].Title != "Detected recursion in file imports" {
+		t.Fatalf("expected the recursive import to be detected, got %v", errs)
+	}
+	if errs[0].Span.File != b {
+		t.Errorf("expected the error to be reported in %q, got %q", b, errs[0].Span.File)
+	}
+	if chain := serror.Default().ImportedFrom(b); len(chain) != 2 || chain[1].File != "test" {
+		t.Errorf("unexpected import chain %v", chain)
+	}
+}
+
+func TestParserLoadRelative(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "a.phia"), []byte("(load \"./a.phia\")"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+	in := "(load \"a.phia\")"
+	serror.SetDefault(serror.NewFormatter(&core.CONF, in, "test", nil))
+	New(lexer.New(strings.NewReader(in), "test").Lex(), "test").Parse()
+	errs := serror.Default().Errors()
+	if len(errs) != 1 || errs[0].Title != "Detected recursion in file imports" {
+		t.Fatalf("expected a.phia and ./a.phia to be detected as the same file, got %v", errs)
+	}
+}
@@ -0,0 +1,220 @@
+// profile measures the time spent in and the amount of calls of each sophia
+// function and each source line while consts.PROFILE is set. The evaluator
+// enters and leaves a frame for each function call and each statement, the
+// time between is attributed to the frame.
+//
+// The self time of a frame excludes the time spent in frames entered while
+// it was entered, the total time includes it. Recursive calls are only
+// included once in the total time of a function or line.
+package profile
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+)
+
+// measurements of a function or line
+type Entry struct {
+	Name  string
+	Calls int
+	Self  time.Duration
+	Total time.Duration
+}
+
+type line struct {
+	file string
+	line int
+}
+
+type frame struct {
+	entry *Entry
+	start time.Time
+	// time spent in frames entered by this frame
+	nested time.Duration
+}
+
+var (
+	functions = map[string]*Entry{}
+	lines     = map[line]*Entry{}
+	// self time of each stack of function names, see WriteFolded
+	folded = map[string]time.Duration{}
+
+	callStack      = make([]frame, 0, 64)
+	statementStack = make([]frame, 0, 64)
+	// stack of function names, joined to the keys of folded
+	names = make([]string, 0, 64)
+)
+
+// clears all measurements and enters the top level frame, called before
+// evaluating a program
+func Start() {
+	clear(functions)
+	clear(lines)
+	clear(folded)
+	callStack = callStack[:0]
+	statementStack = statementStack[:0]
+	names = names[:0]
+	EnterFunction(serror.TOP_LEVEL)
+}
+
+// leaves all frames still entered, such as the top level frame or the frames
+// of a program stopped by a runtime error
+func Stop() {
+	for len(statementStack) != 0 {
+		LeaveStatement()
+	}
+	for len(callStack) != 0 {
+		LeaveFunction()
+	}
+}
+
+// enters the frame of the function called name
+func EnterFunction(name string) {
+	e, ok := functions[name]
+	if !ok {
+		e = &Entry{Name: name}
+		functions[name] = e
+	}
+	e.Calls++
+	names = append(names, name)
+	callStack = append(callStack, frame{entry: e, start: time.Now()})
+}
+
+// leaves the innermost function frame
+func LeaveFunction() {
+	f := leave(&callStack)
+	folded[strings.Join(names, ";")] += f
+	names = names[:len(names)-1]
+}
+
+// enters the frame of the statement at span, statements are attributed to
+// the line they start on
+func EnterStatement(span token.Span) {
+	l := line{span.File, span.Start.Line + 1}
+	e, ok := lines[l]
+	if !ok {
+		e = &Entry{Name: fmt.Sprintf("%s:%d", l.file, l.line)}
+		lines[l] = e
+	}
+	e.Calls++
+	statementStack = append(statementStack, frame{entry: e, start: time.Now()})
+}
+
+// leaves the innermost statement frame
+func LeaveStatement() {
+	leave(&statementStack)
+}
+
+// pops the innermost frame of stack, attributes its time to its entry and
+// the time spent in it to its parent. Returns the self time of the frame.
+func leave(stack *[]frame) time.Duration {
+	s := *stack
+	f := s[len(s)-1]
+	*stack = s[:len(s)-1]
+	elapsed := time.Since(f.start)
+	self := elapsed - f.nested
+	f.entry.Self += self
+	recursive := false
+	for _, outer := range *stack {
+		if outer.entry == f.entry {
+			recursive = true
+			break
+		}
+	}
+	if !recursive {
+		f.entry.Total += elapsed
+	}
+	if len(*stack) != 0 {
+		(*stack)[len(*stack)-1].nested += elapsed
+	}
+	return self
+}
+
+// returns the measured functions, sorted by their self time
+func Functions() []*Entry {
+	return sorted(functions)
+}
+
+// returns the measured lines named file:line, sorted by their self time
+func Lines() []*Entry {
+	return sorted(lines)
+}
+
+func sorted[K comparable](entries map[K]*Entry) []*Entry {
+	res := make([]*Entry, 0, len(entries))
+	for _, e := range entries {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Self == res[j].Self {
+			return res[i].Name < res[j].Name
+		}
+		return res[i].Self > res[j].Self
+	})
+	return res
+}
+
+// writes the n functions and lines with the highest self time
+func Report(w io.Writer, n int) {
+	table := func(title string, entries []*Entry) {
+		var total time.Duration
+		for _, e := range entries {
+			total += e.Self
+		}
+		tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(tw, "self\tself%%\ttotal\tcalls\t%s\n", title)
+		for i, e := range entries {
+			if i == n {
+				break
+			}
+			percent := 0.0
+			if total != 0 {
+				percent = float64(e.Self) / float64(total) * 100
+			}
+			fmt.Fprintf(tw, "%s\t%.1f%%\t%s\t%d\t%s\n", round(e.Self), percent, round(e.Total), e.Calls, e.Name)
+		}
+		tw.Flush()
+	}
+	table("function", Functions())
+	fmt.Fprintln(w)
+	table("line", Lines())
+}
+
+// rounds d to a precision readable in a table
+func round(d time.Duration) time.Duration {
+	switch {
+	case d >= time.Second:
+		return d.Round(time.Millisecond)
+	case d >= time.Millisecond:
+		return d.Round(time.Microsecond)
+	}
+	return d
+}
+
+// writes the self time of each stack of functions in nanoseconds in the
+// folded stack format, one stack per line, such as
+//
+//	<top level>;fib;fib 1250
+//
+// used by flame graph tools like https://github.com/brendangregg/FlameGraph
+// and https://www.speedscope.app
+func WriteFolded(w io.Writer) error {
+	stacks := make([]string, 0, len(folded))
+	for s := range folded {
+		stacks = append(stacks, s)
+	}
+	sort.Strings(stacks)
+	b := bufio.NewWriter(w)
+	for _, s := range stacks {
+		fmt.Fprintf(b, "%s %d\n", s, folded[s].Nanoseconds())
+	}
+	return b.Flush()
+}
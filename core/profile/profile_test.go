@@ -0,0 +1,64 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xnacly/sophia/core/token"
+)
+
+func TestProfile(t *testing.T) {
+	span := func(line int) token.Span {
+		return token.Span{File: "fib.phia", Start: token.Position{Line: line}}
+	}
+	Start()
+	EnterStatement(span(0))
+	EnterFunction("fib")
+	for i := 0; i < 2; i++ {
+		EnterStatement(span(1))
+		EnterFunction("fib")
+		time.Sleep(time.Millisecond)
+		LeaveFunction()
+		LeaveStatement()
+	}
+	LeaveFunction()
+	// left by Stop
+	EnterFunction("println")
+	Stop()
+
+	functions := map[string]*Entry{}
+	for _, e := range Functions() {
+		functions[e.Name] = e
+		if e.Self > e.Total {
+			t.Errorf("wanted the self time of %s to be at most its total time, got %s and %s", e.Name, e.Self, e.Total)
+		}
+	}
+	if fib := functions["fib"]; fib == nil || fib.Calls != 3 || fib.Total < 2*time.Millisecond || fib.Total > functions["<top level>"].Total {
+		t.Errorf("wanted fib to be called 3 times, recursive calls included once in its total time, got %+v", fib)
+	}
+	if Functions()[0].Name != "fib" {
+		t.Errorf("wanted fib to have the highest self time, got %s", Functions()[0].Name)
+	}
+	lines := Lines()
+	if len(lines) != 2 || lines[0].Name != "fib.phia:2" || lines[0].Calls != 2 || lines[1].Name != "fib.phia:1" {
+		t.Errorf("wanted fib.phia:2 evaluated twice before fib.phia:1, got %+v", lines)
+	}
+
+	b := &strings.Builder{}
+	WriteFolded(b)
+	stacks := make([]string, 0)
+	for _, l := range strings.Split(strings.TrimSpace(b.String()), "\n") {
+		// flame graph tools split stacks and values at the last space
+		stacks = append(stacks, l[:strings.LastIndex(l, " ")])
+	}
+	if exp := "<top level>,<top level>;fib,<top level>;fib;fib,<top level>;println"; strings.Join(stacks, ",") != exp {
+		t.Errorf("wanted stacks %s, got %s", exp, strings.Join(stacks, ","))
+	}
+
+	b.Reset()
+	Report(b, 1)
+	if !strings.Contains(b.String(), "fib.phia:2") || strings.Contains(b.String(), "println") {
+		t.Errorf("wanted only the slowest function and line, got:\n%s", b.String())
+	}
+}
@@ -0,0 +1,76 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/checker"
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/parser"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+// sophia check [path ...], statically analyses the given files and all .phia
+// files in the given directories without evaluating them
+func checkCommand(args []string) int {
+	flags := flag.NewFlagSet("check", flag.ExitOnError)
+	conf := newConfFlags(flags, false)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sophia check [-dbg] [-all-errors] [-error-format format] path ...")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if err := conf.apply(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return 2
+	}
+	files, err := sourceFiles(flags.Args(), ".phia")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	code := 0
+	// the errors of all files are combined into a single sarif log
+	formatters := make([]*serror.ErrorFormatter, 0, len(files))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open file: %s\n", err)
+			code = 1
+			continue
+		}
+		if !checkSource(string(content), file) {
+			code = 1
+		}
+		formatters = append(formatters, serror.Default())
+	}
+	if core.CONF.ErrorFormat == serror.FORMAT_SARIF {
+		serror.WriteSARIF(os.Stdout, formatters...)
+	}
+	return code
+}
+
+// lexes, parses and checks src, displays all found errors
+func checkSource(src string, filename string) bool {
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, filename, nil))
+	tokens := lexer.New(strings.NewReader(src), filename).Lex()
+	if !serror.HasErrors() {
+		ast := parser.New(tokens, filename).Parse()
+		if !serror.HasErrors() {
+			checker.Check(ast)
+			checker.CheckTypes(ast)
+		}
+	}
+	if core.CONF.ErrorFormat != serror.FORMAT_SARIF {
+		serror.Display()
+	}
+	return !serror.HasErrors()
+}
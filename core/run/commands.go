@@ -0,0 +1,13 @@
+package run
+
+// subcommands of the sophia cli, invoked via sophia <command> [flags] [args],
+// return the exit code of the process
+var commands = map[string]func(args []string) int{
+	"fmt":     formatCommand,
+	"check":   checkCommand,
+	"lsp":     lspCommand,
+	"explain": explainCommand,
+	"test":    testCommand,
+	"run":     runCommand,
+	"debug":   debugCommand,
+}
@@ -0,0 +1,91 @@
+package run
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/xnacly/sophia/core/alloc"
+	"github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/token"
+)
+
+// completes keywords, built-ins, functions, variables, modules and module
+// members in the repl
+type completer struct{}
+
+// returns the remaining characters of all candidates starting with the
+// word in front of the cursor and the length of the word
+func (completer) Do(line []rune, pos int) ([][]rune, int) {
+	start := pos
+	for start > 0 && isWordRune(line[start-1]) {
+		start--
+	}
+	word := string(line[start:pos])
+	if word == "" {
+		return nil, 0
+	}
+
+	res := make([][]rune, 0)
+	for _, c := range candidates(word) {
+		if strings.HasPrefix(c, word) && c != word {
+			res = append(res, []rune(c[len(word):]))
+		}
+	}
+	return res, len([]rune(word))
+}
+
+// identifiers may contain dashes and underscores, module members are
+// accessed via ::
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == ':'
+}
+
+// returns the sorted names available for completing word: members of the
+// module for module::member, otherwise keywords, built-ins, functions,
+// variables and modules
+func candidates(word string) []string {
+	names := map[string]bool{}
+	if module, _, ok := strings.Cut(word, "::"); ok {
+		if m, ok := consts.MODULE_TABLE[module].(*expr.Module); ok {
+			for _, c := range m.Children {
+				f, ok := c.(*expr.Func)
+				if !ok {
+					continue
+				}
+				if name, ok := f.Name.(*expr.Ident); ok {
+					// members of used modules are already prefixed
+					names[module+"::"+strings.TrimPrefix(name.Name, module+"::")] = true
+				}
+			}
+		}
+	} else {
+		for name := range token.KEYWORD_MAP {
+			names[name] = true
+		}
+		for name := range builtin.BUILTINS {
+			names[name] = true
+		}
+		for name, key := range alloc.Default.Functions {
+			if _, ok := consts.FUNC_TABLE[key]; ok {
+				names[name] = true
+			}
+		}
+		for name, key := range alloc.Default.Variables {
+			if _, ok := consts.SYMBOL_TABLE[key]; ok {
+				names[name] = true
+			}
+		}
+		for name := range consts.MODULE_TABLE {
+			names[name+"::"] = true
+		}
+	}
+	res := make([]string, 0, len(names))
+	for name := range names {
+		res = append(res, name)
+	}
+	sort.Strings(res)
+	return res
+}
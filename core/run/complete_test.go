@@ -0,0 +1,43 @@
+package run
+
+import (
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+func TestComplete(t *testing.T) {
+	src := "(let value 1)(fun square [n] (* n n))(module person (fun str [p] p))"
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, "test", io.Discard))
+	if _, err := Run(strings.NewReader(src), "test"); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		line string
+		exp  []string
+	}{
+		{line: "(prin", exp: []string{"tln"}},
+		{line: "(la", exp: []string{"mbda"}},
+		{line: "(squ", exp: []string{"are"}},
+		{line: "(println val", exp: []string{"ue"}},
+		{line: "(pers", exp: []string{"on::"}},
+		{line: "(person::", exp: []string{"str"}},
+		{line: "(println ", exp: nil},
+	}
+	for _, test := range tests {
+		got, length := completer{}.Do([]rune(test.line), len([]rune(test.line)))
+		suffixes := make([]string, len(got))
+		for i, g := range got {
+			suffixes[i] = string(g)
+		}
+		if strings.Join(suffixes, ",") != strings.Join(test.exp, ",") {
+			t.Errorf("wanted %q for %q, got %q", test.exp, test.line, suffixes)
+		}
+		if len(got) != 0 && length == 0 {
+			t.Errorf("expected the length of the completed word for %q", test.line)
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+// flags configuring the interpreter via core.CONF, shared by sophia and its
+// commands
+type confFlags struct {
+	dbg         *bool
+	allErrors   *bool
+	errorFormat *string
+	// nil for commands not evaluating code
+	typeCheck *bool
+}
+
+// registers the flags on flags, -type-check only if evaluates is set
+func newConfFlags(flags *flag.FlagSet, evaluates bool) *confFlags {
+	c := &confFlags{
+		dbg:         flags.Bool("dbg", false, "enable debug logs"),
+		allErrors:   flags.Bool("all-errors", false, "display all found errors"),
+		errorFormat: flags.String("error-format", serror.FORMAT_TEXT, "format errors are displayed in: text, json or sarif"),
+	}
+	if evaluates {
+		c.typeCheck = flags.Bool("type-check", false, "check types before evaluating, see sophia check")
+	}
+	return c
+}
+
+// replaces core.CONF with the configuration given by the flags, fails for
+// unknown error formats
+func (c *confFlags) apply() error {
+	if !serror.KnownFormat(*c.errorFormat) {
+		return fmt.Errorf("Unknown error format %q, expected text, json or sarif", *c.errorFormat)
+	}
+	core.CONF = core.Config{
+		Debug:       *c.dbg,
+		AllErrors:   *c.allErrors,
+		ErrorFormat: *c.errorFormat,
+		TypeCheck:   c.typeCheck != nil && *c.typeCheck,
+	}
+	if *c.dbg {
+		log.SetFlags(log.Ltime | log.Lmicroseconds)
+	} else {
+		log.SetFlags(0)
+	}
+	return nil
+}
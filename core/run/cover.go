@@ -0,0 +1,68 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/cover"
+)
+
+// flags for recording and reporting coverage, shared by sophia run and
+// sophia test
+type coverFlags struct {
+	summary *bool
+	lcov    *string
+	listing *string
+}
+
+func newCoverFlags(flags *flag.FlagSet) *coverFlags {
+	return &coverFlags{
+		summary: flags.Bool("cover", false, "record coverage and display the covered statements per file"),
+		lcov:    flags.String("cover-lcov", "", "record coverage and write it to `file` in the lcov format"),
+		listing: flags.String("cover-listing", "", "record coverage and write the sources annotated with the evaluations of each line to `file`"),
+	}
+}
+
+// enables recording coverage if any of the coverage flags is set
+func (c *coverFlags) start() {
+	consts.COVER = *c.summary || *c.lcov != "" || *c.listing != ""
+}
+
+// writes the recorded coverage as requested by the flags, the summary is
+// written to w
+func (c *coverFlags) report(w io.Writer) error {
+	if !consts.COVER {
+		return nil
+	}
+	files := cover.Files()
+	if *c.summary {
+		cover.Summary(w, files)
+	}
+	if *c.lcov != "" {
+		if err := writeFile(*c.lcov, func(w io.Writer) error { return cover.Lcov(w, files) }); err != nil {
+			return fmt.Errorf("failed to write lcov coverage: %w", err)
+		}
+	}
+	if *c.listing != "" {
+		if err := writeFile(*c.listing, func(w io.Writer) error { return cover.Listing(w, files) }); err != nil {
+			return fmt.Errorf("failed to write coverage listing: %w", err)
+		}
+	}
+	return nil
+}
+
+// creates file and writes to it via write
+func writeFile(file string, write func(w io.Writer) error) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,55 @@
+package run
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/debugger"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+// sophia debug file, evaluates file paused before its first statement,
+// commands for setting breakpoints, stepping and inspecting the variables are
+// read from stdin
+func debugCommand(args []string) int {
+	flags := flag.NewFlagSet("debug", flag.ExitOnError)
+	conf := newConfFlags(flags, true)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sophia debug [-dbg] [-all-errors] [-error-format format] [-type-check] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if err := conf.apply(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+
+	file := flags.Arg(0)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open file: %s\n", err)
+		return 1
+	}
+	serror.SetDefault(serror.NewFormatter(&core.CONF, string(content), file, nil))
+	fmt.Println("debugging", file, "- type help for a list of commands")
+	consts.DEBUGGER = debugger.New(file, os.Stdin, os.Stdout)
+	defer func() { consts.DEBUGGER = nil }()
+	if _, err := Run(bytes.NewReader(content), file); errors.Is(err, debugger.ErrQuit) {
+		// quitting the debugger is not an error
+		return 0
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "\n"+err.Error())
+		return 1
+	}
+	fmt.Println("evaluation finished")
+	return 0
+}
@@ -0,0 +1,32 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/xnacly/sophia/core/serror"
+)
+
+// sophia explain <code>, prints the explanation of an error code, such as
+// S0024
+func explainCommand(args []string) int {
+	flags := flag.NewFlagSet("explain", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sophia explain code")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	code, ok := serror.LookupCode(flags.Arg(0))
+	if !ok {
+		fmt.Fprintf(os.Stderr, "Unknown error code %q, codes are of the form S0024\n", flags.Arg(0))
+		return 1
+	}
+	fmt.Printf("%s[%s]: %s\n\n%s\n", code.Severity, code.Code, code.Title, code.Explanation)
+	return 0
+}
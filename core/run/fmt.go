@@ -0,0 +1,125 @@
+package run
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/formatter"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+// sophia fmt [-w] [-check] [path ...], formats the given files and all .phia
+// files in the given directories, reads from stdin if no path is given
+func formatCommand(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false, "write the result to the source file instead of stdout")
+	check := flags.Bool("check", false, "list files not formatted and exit with a non zero exit code if there are any")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sophia fmt [-w] [-check] [path ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		buf := bytes.Buffer{}
+		buf.ReadFrom(os.Stdin)
+		formatted, ok := formatSource(buf.String(), "stdin")
+		if !ok {
+			return 1
+		}
+		if *check {
+			if formatted != buf.String() {
+				fmt.Println("stdin")
+				return 1
+			}
+			return 0
+		}
+		fmt.Print(formatted)
+		return 0
+	}
+
+	files, err := sourceFiles(flags.Args(), ".phia")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	code := 0
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to open file: %s\n", err)
+			code = 1
+			continue
+		}
+		formatted, ok := formatSource(string(content), file)
+		if !ok {
+			code = 1
+			continue
+		}
+		changed := formatted != string(content)
+		switch {
+		case *check:
+			if changed {
+				fmt.Println(file)
+				code = 1
+			}
+		case *write:
+			if changed {
+				if err := os.WriteFile(file, []byte(formatted), 0644); err != nil {
+					fmt.Fprintf(os.Stderr, "Failed to write file: %s\n", err)
+					code = 1
+				}
+			}
+		default:
+			io.WriteString(os.Stdout, formatted)
+		}
+	}
+	return code
+}
+
+// formats src, displays errors on stderr
+func formatSource(src string, filename string) (string, bool) {
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, filename, os.Stderr))
+	formatted := formatter.Format(src, filename)
+	if serror.HasErrors() {
+		serror.Display()
+		return "", false
+	}
+	return formatted, true
+}
+
+// expands directories in paths to the files ending with suffix contained in
+// them, such as .phia
+func sourceFiles(paths []string, suffix string) ([]string, error) {
+	files := make([]string, 0, len(paths))
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		err = filepath.WalkDir(path, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && strings.HasSuffix(path, suffix) {
+				files = append(files, path)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
@@ -0,0 +1,110 @@
+package run
+
+import (
+	"io"
+	"strings"
+
+	"github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+)
+
+// ansi escape codes for highlighting repl input
+const (
+	ANSI_RESET   = "\033[0m"
+	ANSI_MATCH   = "\033[1;4m"
+	ANSI_KEYWORD = "\033[95m"
+	ANSI_BUILTIN = "\033[96m"
+	ANSI_CALL    = "\033[94m"
+	ANSI_STRING  = "\033[92m"
+	ANSI_NUMBER  = "\033[93m"
+	ANSI_COMMENT = "\033[90m"
+	ANSI_ERROR   = "\033[91m"
+)
+
+// highlights the input of the repl based on the token types of the lexer and
+// the brace matching the brace at or before the cursor
+type painter struct {
+	color bool
+}
+
+// the repl is only highlighted if its output is colored the same way errors
+// are, see serror.Colored
+func newPainter(w io.Writer) *painter {
+	return &painter{color: serror.Colored(w)}
+}
+
+func (p *painter) Paint(line []rune, pos int) []rune {
+	if !p.color || len(line) == 0 {
+		return line
+	}
+	styles := highlight(line)
+	pairs := scanInput(line).pairs
+	at := -1
+	if _, ok := pairs[pos]; ok && pos < len(line) {
+		at = pos
+	} else if _, ok := pairs[pos-1]; ok {
+		at = pos - 1
+	}
+	if at != -1 {
+		styles[at] = ANSI_MATCH
+		styles[pairs[at]] = ANSI_MATCH
+	}
+	b := strings.Builder{}
+	current := ""
+	for i, r := range line {
+		if styles[i] != current {
+			if current != "" {
+				b.WriteString(ANSI_RESET)
+			}
+			b.WriteString(styles[i])
+			current = styles[i]
+		}
+		b.WriteRune(r)
+	}
+	if current != "" {
+		b.WriteString(ANSI_RESET)
+	}
+	return []rune(b.String())
+}
+
+// computes the ansi escape code for each rune of line, empty for runes not
+// highlighted
+func highlight(line []rune) []string {
+	styles := make([]string, len(line))
+	src := string(line)
+	tokens, _ := lexInput(src)
+	runeAt := runeIndexes(src)
+
+	for j, t := range tokens {
+		style := ""
+		switch {
+		case t.Type >= token.LET && t.Type <= token.LAMBDA:
+			style = ANSI_KEYWORD
+		case t.Type == token.STRING || t.Type == token.TEMPLATE_STRING:
+			style = ANSI_STRING
+		case t.Type == token.FLOAT || t.Type == token.DECIMAL || t.Type == token.BOOL || t.Type == token.FORMAT:
+			style = ANSI_NUMBER
+		case t.Type == token.COMMENT || t.Type == token.DOC_COMMENT:
+			style = ANSI_COMMENT
+		case t.Type == token.UNKNOWN:
+			style = ANSI_ERROR
+		case t.Type == token.IDENT && j > 0 && tokens[j-1].Type == token.LEFT_BRACE:
+			style = ANSI_CALL
+			if _, ok := builtin.BUILTINS[t.Raw]; ok {
+				style = ANSI_BUILTIN
+			}
+		}
+		if style == "" {
+			continue
+		}
+		start, end := t.Span().Start.Offset, t.Span().End.Offset
+		if start < 0 || end > len(src) {
+			continue
+		}
+		for k := runeAt[start]; k < runeAt[end]; k++ {
+			styles[k] = style
+		}
+	}
+	return styles
+}
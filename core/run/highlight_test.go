@@ -0,0 +1,58 @@
+package run
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlight(t *testing.T) {
+	line := []rune(`(let ä "ö") (println 1 true) ;; c`)
+	styles := highlight(line)
+	tests := []struct {
+		text  string
+		style string
+	}{
+		{text: "let", style: ANSI_KEYWORD},
+		{text: "ä", style: ""},
+		{text: `"ö"`, style: ANSI_STRING},
+		{text: "println", style: ANSI_BUILTIN},
+		{text: "1", style: ANSI_NUMBER},
+		{text: "true", style: ANSI_NUMBER},
+		{text: ";; c", style: ANSI_COMMENT},
+	}
+	for _, test := range tests {
+		start := strings.Index(string(line), test.text)
+		start = len([]rune(string(line)[:start]))
+		for i := start; i < start+len([]rune(test.text)); i++ {
+			if styles[i] != test.style {
+				t.Errorf("wanted %q for %q, got %q", test.style, test.text, styles[i])
+				break
+			}
+		}
+	}
+}
+
+func TestPaintMatchingBraces(t *testing.T) {
+	p := &painter{color: true}
+	line := []rune("(a (b c))")
+	tests := []struct {
+		pos int
+		exp string
+	}{
+		{pos: 0, exp: ANSI_MATCH + "(" + ANSI_RESET + ANSI_CALL + "a" + ANSI_RESET + " (" + ANSI_CALL + "b" + ANSI_RESET + " c)" + ANSI_MATCH + ")" + ANSI_RESET},
+		{pos: 7, exp: "(" + ANSI_CALL + "a" + ANSI_RESET + " " + ANSI_MATCH + "(" + ANSI_RESET + ANSI_CALL + "b" + ANSI_RESET + " c" + ANSI_MATCH + ")" + ANSI_RESET + ")"},
+		{pos: 9, exp: ANSI_MATCH + "(" + ANSI_RESET + ANSI_CALL + "a" + ANSI_RESET + " (" + ANSI_CALL + "b" + ANSI_RESET + " c)" + ANSI_MATCH + ")" + ANSI_RESET},
+	}
+	for _, test := range tests {
+		if got := string(p.Paint(line, test.pos)); got != test.exp {
+			t.Errorf("wanted %q for cursor at %d, got %q", test.exp, test.pos, got)
+		}
+	}
+}
+
+func TestPaintNonTerminal(t *testing.T) {
+	line := "(let a 1)"
+	if got := string(newPainter(&strings.Builder{}).Paint([]rune(line), 0)); got != line {
+		t.Errorf("expected no highlighting for non terminal output, got %q", got)
+	}
+}
@@ -0,0 +1,104 @@
+package run
+
+import (
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/token"
+)
+
+const (
+	PROMPT              = "ß > "
+	CONTINUATION_PROMPT = "... "
+)
+
+// lexes the repl input src including its comments, returns the tokens and
+// the errors the lexer found
+func lexInput(src string) ([]*token.Token, []*serror.Error) {
+	// lexing incomplete input adds errors, which must neither be displayed
+	// nor reported for the next evaluated input
+	defer serror.SetDefault(serror.Default())
+	errs := serror.NewFormatter(&core.CONF, src, "repl", io.Discard)
+	serror.SetDefault(errs)
+	l := lexer.New(strings.NewReader(src), "repl")
+	l.KeepComments()
+	return l.Lex(), errs.Errors()
+}
+
+// maps the byte offsets of the lexer to rune indexes of src, offsets inside
+// of a multi byte rune are mapped to the next rune
+func runeIndexes(src string) []int {
+	runeAt := make([]int, len(src)+1)
+	i := 0
+	for offset := range src {
+		runeAt[offset] = i
+		i++
+	}
+	runeAt[len(src)] = i
+	for offset := len(src) - 1; offset > 0; offset-- {
+		if !utf8.RuneStart(src[offset]) {
+			runeAt[offset] = runeAt[offset+1]
+		}
+	}
+	return runeAt
+}
+
+// result of scanning repl input for braces, brackets and curly braces
+type scan struct {
+	// positions of matching braces, each brace is mapped to its counterpart
+	pairs map[int]int
+	// amount of braces opened but not closed
+	open int
+	// amount of braces closed without being opened
+	unmatched int
+	// src ends inside of a string or block comment
+	unterminated bool
+}
+
+// scans the tokens of src for braces, brackets and curly braces, the
+// interpolations of template strings are not tokens and therefore skipped
+func scanInput(src []rune) scan {
+	s := scan{pairs: map[int]int{}}
+	text := string(src)
+	tokens, errs := lexInput(text)
+	runeAt := runeIndexes(text)
+	stack := make([]int, 0)
+	for _, t := range tokens {
+		offset := t.Span().Start.Offset
+		if offset < 0 || offset > len(text) {
+			continue
+		}
+		switch t.Type {
+		case token.LEFT_BRACE, token.LEFT_BRACKET, token.LEFT_CURLY:
+			stack = append(stack, runeAt[offset])
+		case token.RIGHT_BRACE, token.RIGHT_BRACKET, token.RIGHT_CURLY:
+			if len(stack) == 0 {
+				s.unmatched++
+				continue
+			}
+			open := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			s.pairs[open] = runeAt[offset]
+			s.pairs[runeAt[offset]] = open
+		}
+	}
+	for _, err := range errs {
+		if strings.HasPrefix(err.Title, "Unterminated") {
+			s.unterminated = true
+		}
+	}
+	s.open = len(stack)
+	return s
+}
+
+// reports whether src requires further lines: a brace, bracket, curly
+// brace, string or block comment is not yet closed. Input containing
+// unmatched closing braces is complete, to report the error immediately.
+func incomplete(src string) bool {
+	s := scanInput([]rune(src))
+	return s.unmatched == 0 && (s.open > 0 || s.unterminated)
+}
@@ -0,0 +1,31 @@
+package run
+
+import "testing"
+
+func TestIncomplete(t *testing.T) {
+	tests := []struct {
+		src string
+		exp bool
+	}{
+		{src: "(println 1)", exp: false},
+		{src: "(fun square [n]", exp: true},
+		{src: "(fun square [n]\n  (* n n))", exp: false},
+		{src: "(let a {name: \"anon\"", exp: true},
+		{src: "(println \"(\")", exp: false},
+		{src: "(println \"a\\\")", exp: true},
+		{src: "(println `a\\`)", exp: false},
+		{src: "(println 'a {b} ", exp: true},
+		{src: "(println '{(+ 1 2)}')", exp: false},
+		{src: "(println '{(+ 1", exp: true},
+		{src: "(println 1) ;; (", exp: false},
+		{src: "#| (\n#| nested |#", exp: true},
+		{src: "#| ( |#", exp: false},
+		{src: "(println a#[0])", exp: false},
+		{src: "(println 1))(", exp: false},
+	}
+	for _, test := range tests {
+		if got := incomplete(test.src); got != test.exp {
+			t.Errorf("wanted %t for %q, got %t", test.exp, test.src, got)
+		}
+	}
+}
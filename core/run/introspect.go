@@ -0,0 +1,230 @@
+package run
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/alloc"
+	"github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/checker"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/expr"
+	"github.com/xnacly/sophia/core/lexer"
+	"github.com/xnacly/sophia/core/parser"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/shared"
+	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
+)
+
+// writes all defined variables with their value and type, sorted by name
+func variables(w io.Writer) {
+	names := make([]string, 0)
+	for name, key := range alloc.Default.Variables {
+		if _, ok := consts.SYMBOL_TABLE[key]; ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "name\tvalue\ttype")
+	for _, name := range names {
+		v := consts.SYMBOL_TABLE[alloc.Default.Variables[name]]
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", name, shared.Repr(v), types.Of(v))
+	}
+	tw.Flush()
+}
+
+// returns all functions defined via fun, sorted by name. Functions
+// redefined in the session are only returned once, by their latest definition.
+func functions() []*expr.Func {
+	res := make([]*expr.Func, 0)
+	for _, key := range alloc.Default.Functions {
+		if f, ok := consts.FUNC_TABLE[key].(*expr.Func); ok {
+			res = append(res, f)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Signature() < res[j].Signature()
+	})
+	return res
+}
+
+// writes all functions defined via fun with their parameters and the
+// location of their definition
+func listFunctions(w io.Writer) {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "function\tdefined at")
+	for _, f := range functions() {
+		fmt.Fprintf(tw, "%s\t%s\n", f.Signature(), location(f.Token))
+	}
+	tw.Flush()
+}
+
+// formats the position of t as file:line:column
+func location(t *token.Token) string {
+	return fmt.Sprintf("%s:%d:%d", t.File, t.Line+1, t.LinePos+1)
+}
+
+// writes the documentation of the function or built-in called name
+func doc(w io.Writer, name string) error {
+	if sig, ok := builtin.BUILTINS[name]; ok {
+		params := strings.Join(sig.Params, " ")
+		if sig.Variadic {
+			params += "..."
+		}
+		fmt.Fprintf(w, "(%s %s) -> %s, built-in\n\n%s\n", name, params, sig.Returns, sig.Doc)
+		return nil
+	}
+	for _, f := range functions() {
+		if ident, ok := f.Name.(*expr.Ident); ok && ident.Name == name {
+			fmt.Fprintf(w, "%s, defined at %s\n", f.Signature(), location(f.Token))
+			if f.Doc != "" {
+				fmt.Fprintf(w, "\n%s\n", f.Doc)
+			}
+			return nil
+		}
+	}
+	return fmt.Errorf("no function or built-in named %q", name)
+}
+
+// lexes src, displaying errors
+func tokenize(src string) ([]*token.Token, error) {
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, "repl", nil))
+	tokens := lexer.New(strings.NewReader(src), "repl").Lex()
+	if serror.HasErrors() {
+		serror.Display()
+		return nil, errors.New("Syntax errors found")
+	}
+	return tokens, nil
+}
+
+// lexes and parses src without evaluating it, displaying errors
+func parse(src string) ([]types.Node, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	ast := parser.New(tokens, "repl").Parse()
+	if serror.HasErrors() {
+		serror.Display()
+		return nil, errors.New("Semantic errors found")
+	}
+	return ast, nil
+}
+
+// writes the tokens of src, one per line
+func listTokens(w io.Writer, src string) error {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return err
+	}
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "position\ttype\traw")
+	for _, t := range tokens {
+		if t.Type == token.EOF {
+			continue
+		}
+		fmt.Fprintf(tw, "%d:%d\t%s\t%q\n", t.Line+1, t.LinePos+1, token.TOKEN_NAME_MAP[t.Type], t.Raw)
+	}
+	tw.Flush()
+	return nil
+}
+
+// writes the tree of nodes src is parsed to, children are indented below
+// their parent
+func printAST(w io.Writer, src string) error {
+	ast, err := parse(src)
+	if err != nil {
+		return err
+	}
+	var print func(n types.Node, depth int)
+	print = func(n types.Node, depth int) {
+		if n == nil || reflect.ValueOf(n).IsNil() {
+			return
+		}
+		fmt.Fprintf(w, "%s%s", strings.Repeat("  ", depth), reflect.TypeOf(n).Elem().Name())
+		if t := n.GetToken(); t != nil && t.Raw != "" {
+			fmt.Fprintf(w, " %q", t.Raw)
+		}
+		fmt.Fprintln(w)
+		for _, c := range astChildren(n) {
+			print(c, depth+1)
+		}
+	}
+	for _, n := range ast {
+		print(n, 0)
+	}
+	return nil
+}
+
+// returns the children of n including the names and parameters of
+// definitions, which are omitted by checker.Children
+func astChildren(n types.Node) []types.Node {
+	switch n := n.(type) {
+	case *expr.Func:
+		return append([]types.Node{n.Name, n.Params}, n.Body...)
+	case *expr.Lambda:
+		return append([]types.Node{n.Params}, n.Body...)
+	case *expr.For:
+		return append([]types.Node{n.Params, n.LoopOver}, n.Body...)
+	case *expr.Var:
+		return append([]types.Node{n.Ident}, n.Value...)
+	}
+	return checker.Children(n)
+}
+
+// writes the type of the value of each expression of src
+func printType(w io.Writer, src string) error {
+	ast, err := parse(src)
+	if err != nil {
+		return err
+	}
+	values, err := evaluate(ast)
+	if err != nil {
+		return err
+	}
+	for _, v := range values {
+		fmt.Fprintln(w, types.Of(v))
+	}
+	return nil
+}
+
+// evaluates all nodes, runtime errors are displayed and returned
+func evaluate(ast []types.Node) (values []any, err error) {
+	defer func() {
+		if core.CONF.Debug {
+			return
+		}
+		if e := recover(); e != nil {
+			serror.Display()
+			err = fmt.Errorf("Runtime error found, stopped evaluation: %v", e)
+		}
+	}()
+	values = make([]any, len(ast))
+	for i, n := range ast {
+		values[i] = n.Eval()
+	}
+	return values, nil
+}
+
+// removes all variables, functions and modules defined in the session,
+// built-ins and functions registered via embedding are kept
+func reset() {
+	for key, f := range consts.FUNC_TABLE {
+		if _, ok := f.(*expr.Func); ok {
+			delete(consts.FUNC_TABLE, key)
+		}
+	}
+	clear(consts.SYMBOL_TABLE)
+	clear(consts.SCOPE_TABLE)
+	clear(consts.MODULE_TABLE)
+	consts.RETURN = consts.Return{}
+	consts.CALL_STACK = consts.CALL_STACK[:0]
+}
@@ -0,0 +1,60 @@
+package run
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+func TestIntrospection(t *testing.T) {
+	reset()
+	src := ";;; squares n\n(fun square [n] (* n n))\n(let values [1 2])"
+	serror.SetDefault(serror.NewFormatter(&core.CONF, src, "test", io.Discard))
+	if _, err := Run(strings.NewReader(src), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		print    func(w io.Writer) error
+		contains []string
+	}{
+		{name: "variables", print: func(w io.Writer) error { variables(w); return nil }, contains: []string{"values  [1 2]  array"}},
+		{name: "functions", print: func(w io.Writer) error { listFunctions(w); return nil }, contains: []string{"(fun square [n])  test:2:2"}},
+		{name: "doc", print: func(w io.Writer) error { return doc(w, "square") }, contains: []string{"(fun square [n]), defined at test:2:2", "squares n"}},
+		{name: "builtin doc", print: func(w io.Writer) error { return doc(w, "println") }, contains: []string{"(println values...)", "built-in"}},
+		{name: "ast", print: func(w io.Writer) error { return printAST(w, "(let a (+ 1 2))") }, contains: []string{"Var \"let\"\n  Ident \"a\"\n  Add \"+\"\n    Float \"1\"\n    Float \"2\"\n"}},
+		{name: "tokens", print: func(w io.Writer) error { return listTokens(w, "(+ 1 \"a\")") }, contains: []string{"1:4       float   \"1\"", "1:6       string  \"a\""}},
+		{name: "type", print: func(w io.Writer) error { return printType(w, "(square 2)(let s \"a\")") }, contains: []string{"float\nstring\n"}},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			b := &bytes.Buffer{}
+			if err := test.print(b); err != nil {
+				t.Fatal(err)
+			}
+			for _, c := range test.contains {
+				if !strings.Contains(b.String(), c) {
+					t.Errorf("expected %q in %q", c, b.String())
+				}
+			}
+		})
+	}
+
+	if err := doc(io.Discard, "unknown"); err == nil {
+		t.Errorf("expected an error for an unknown function")
+	}
+
+	reset()
+	if len(functions()) != 0 || len(consts.SYMBOL_TABLE) != 0 {
+		t.Errorf("expected reset to remove all functions and variables")
+	}
+	if _, ok := consts.FUNC_TABLE[0]; ok || len(consts.FUNC_TABLE) == 0 {
+		t.Errorf("expected built-ins to be kept")
+	}
+}
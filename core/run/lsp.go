@@ -0,0 +1,26 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/xnacly/sophia/core/lsp"
+)
+
+// sophia lsp, starts the language server, speaking the language server
+// protocol over stdin and stdout
+func lspCommand(args []string) int {
+	flags := flag.NewFlagSet("lsp", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sophia lsp")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	if err := lsp.NewServer(os.Stdin, os.Stdout).Serve(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,48 @@
+package run
+
+import (
+	"flag"
+	"fmt"
+	"io"
+
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/profile"
+)
+
+// flags for profiling the evaluation, shared by sophia and sophia run
+type profileFlags struct {
+	enabled *bool
+	top     *int
+	folded  *string
+}
+
+func newProfileFlags(flags *flag.FlagSet) *profileFlags {
+	return &profileFlags{
+		enabled: flags.Bool("profile", false, "measure the time spent in and the calls of each function and line, display the slowest"),
+		top:     flags.Int("profile-top", 10, "amount of functions and lines displayed by -profile"),
+		folded:  flags.String("profile-folded", "", "profile and write the time spent in each stack of functions to `file` in the folded stack format used by flame graph tools"),
+	}
+}
+
+// enables profiling the evaluation in Run if -profile or -profile-folded is
+// set
+func (p *profileFlags) start() {
+	consts.PROFILE = *p.enabled || *p.folded != ""
+}
+
+// writes the measurements as requested by the flags, the table of the
+// slowest functions and lines is written to w
+func (p *profileFlags) report(w io.Writer) error {
+	if !consts.PROFILE {
+		return nil
+	}
+	if *p.enabled {
+		profile.Report(w, *p.top)
+	}
+	if *p.folded != "" {
+		if err := writeFile(*p.folded, profile.WriteFolded); err != nil {
+			return fmt.Errorf("failed to write folded stacks: %w", err)
+		}
+	}
+	return nil
+}
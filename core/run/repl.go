@@ -4,58 +4,216 @@ import (
 	"fmt"
 	"io"
 	"log"
+	"os"
+	"path/filepath"
 	"strings"
+	"time"
 
 	"github.com/chzyer/readline"
 	"github.com/xnacly/sophia/core"
-	"github.com/xnacly/sophia/core/consts"
 	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/shared"
+	"github.com/xnacly/sophia/core/token"
 )
 
+// state of a repl session
+type session struct {
+	rl  *readline.Instance
+	run func(r io.Reader, filename string) ([]string, error)
+	// successfully evaluated inputs, written to a file via ~save
+	inputs []string
+}
+
+// returns the path of the file the repl history is stored in, empty if the
+// user config directory is unknown
+func historyFile() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	dir = filepath.Join(dir, "sophia")
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "history")
+}
+
+// history entries are stored one per line, multi line input is therefore
+// joined into a single line based on its tokens: line comments are dropped,
+// newlines in strings are escaped and raw strings spanning multiple lines are
+// converted into strings
+func historyEntry(input string) string {
+	tokens, _ := lexInput(input)
+	b := strings.Builder{}
+	// source between the last written token and the next one
+	gap := strings.Builder{}
+	pos := 0
+	for _, t := range tokens {
+		start, end := t.Span().Start.Offset, t.Span().End.Offset
+		if start < pos || end > len(input) {
+			continue
+		}
+		gap.WriteString(input[pos:start])
+		pos = end
+		text := input[start:end]
+		switch {
+		case t.Type == token.DOC_COMMENT || t.Type == token.COMMENT && strings.HasPrefix(text, ";"):
+			// line comments end at the end of their line
+			gap.WriteString("\n")
+			continue
+		case t.Type == token.COMMENT:
+			text = strings.ReplaceAll(text, "\n", " ")
+		case t.Type == token.STRING && strings.HasPrefix(text, "`"):
+			if strings.Contains(text, "\n") {
+				text = shared.Repr(t.Raw)
+			}
+		case t.Type == token.STRING:
+			text = strings.ReplaceAll(text, "\n", `\n`)
+		}
+		ws := gap.String()
+		gap.Reset()
+		if strings.TrimSpace(ws) == "" && strings.Contains(ws, "\n") {
+			ws = " "
+		}
+		b.WriteString(ws)
+		b.WriteString(text)
+	}
+	b.WriteString(gap.String())
+	b.WriteString(input[min(pos, len(input)):])
+	return strings.TrimSpace(b.String())
+}
+
 func repl(run func(r io.Reader, filename string) ([]string, error)) {
 	log.SetFlags(0)
 	fmt.Println(`Welcome to the Sophia programming language repl - press <CTRL-D> or <CTRL-C> to quit...`)
 
 	rl, err := readline.NewEx(&readline.Config{
-		Prompt: "ß > ",
+		Prompt:       PROMPT,
+		Painter:      newPainter(os.Stdout),
+		AutoComplete: completer{},
+		HistoryFile:  historyFile(),
+		// multi line input is saved as a single entry once complete
+		DisableAutoSaveHistory: true,
 	})
 	if err != nil {
 		panic(err)
 	}
 	defer rl.Close()
+	s := &session{rl: rl, run: run}
 
+	// lines of the input read so far, input is read until all braces,
+	// strings and comments are closed
+	lines := make([]string, 0)
 	for {
 		line, err := rl.Readline()
-		if err != nil {
+		if err == readline.ErrInterrupt && len(lines) != 0 {
+			// discard the incomplete input
+			lines = lines[:0]
+			rl.SetPrompt(PROMPT)
+			continue
+		} else if err != nil {
 			break
 		}
-		if len(line) == 0 {
+		if len(lines) == 0 && len(strings.TrimSpace(line)) == 0 {
 			continue
 		}
 
-		if line[0] == '~' {
-			switch string(line[1:]) {
-			case "syms":
-				fmt.Printf("%#v\n", consts.SYMBOL_TABLE)
-			case "funs":
-				fmt.Printf("%#v\n", consts.FUNC_TABLE)
-			case "debug":
-				core.CONF.Debug = !core.CONF.Debug
-				log.Printf("toggled debug logging to='%t'", core.CONF.Debug)
-			}
-		} else {
-			var r io.Reader
-			r = strings.NewReader(line)
-			serror.SetDefault(serror.NewFormatter(&core.CONF, line, "repl", nil))
-			val, error := run(r, "repl")
-			if error != nil {
-				log.Println(error)
-			} else {
-				fmt.Println("=")
-				for _, v := range val {
-					fmt.Println(" ", v)
-				}
+		if len(lines) == 0 && line[0] == '~' {
+			rl.SaveHistory(line)
+			if err := s.command(line[1:]); err != nil {
+				log.Println(err)
 			}
+			continue
+		}
+
+		lines = append(lines, line)
+		input := strings.Join(lines, "\n")
+		if incomplete(input) {
+			rl.SetPrompt(CONTINUATION_PROMPT)
+			continue
+		}
+		lines = lines[:0]
+		rl.SetPrompt(PROMPT)
+		rl.SaveHistory(historyEntry(input))
+		if err := s.eval(input); err != nil {
+			log.Println(err)
+		}
+	}
+}
+
+// evaluates input and prints its results, successfully evaluated inputs are
+// recorded for ~save
+func (s *session) eval(input string) error {
+	serror.SetDefault(serror.NewFormatter(&core.CONF, input, "repl", nil))
+	val, error := s.run(strings.NewReader(input), "repl")
+	if error != nil {
+		return error
+	}
+	s.inputs = append(s.inputs, input)
+	fmt.Println("=")
+	for _, v := range val {
+		fmt.Println(" ", v)
+	}
+	return nil
+}
+
+// executes the repl command ~name args, returns the usage of a command if
+// its argument is missing
+func (s *session) command(line string) error {
+	name, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+	arg = strings.TrimSpace(arg)
+	usage := map[string]string{
+		"save":   "~save <file>",
+		"load":   "~load <file>",
+		"ast":    "~ast <expression>",
+		"tokens": "~tokens <expression>",
+		"type":   "~type <expression>",
+		"doc":    "~doc <function>",
+		"time":   "~time <expression>",
+	}
+	if u, ok := usage[name]; ok && arg == "" {
+		return fmt.Errorf("usage: %s", u)
+	}
+	switch name {
+	case "syms":
+		variables(os.Stdout)
+	case "funs":
+		listFunctions(os.Stdout)
+	case "debug":
+		core.CONF.Debug = !core.CONF.Debug
+		log.Printf("toggled debug logging to='%t'", core.CONF.Debug)
+	case "save":
+		src := strings.Join(s.inputs, "\n")
+		if len(s.inputs) != 0 {
+			src += "\n"
+		}
+		if err := os.WriteFile(arg, []byte(src), 0644); err != nil {
+			return fmt.Errorf("failed to save session: %w", err)
+		}
+		log.Printf("saved %d inputs to %q", len(s.inputs), arg)
+	case "load":
+		// loading via the load statement records the file in the session
+		return s.eval("(load " + shared.Repr(arg) + ")")
+	case "ast":
+		return printAST(os.Stdout, arg)
+	case "tokens":
+		return listTokens(os.Stdout, arg)
+	case "type":
+		return printType(os.Stdout, arg)
+	case "doc":
+		return doc(os.Stdout, arg)
+	case "time":
+		start := time.Now()
+		if err := s.eval(arg); err != nil {
+			return err
 		}
+		log.Printf("took %s", time.Since(start))
+	case "reset":
+		reset()
+		s.inputs = s.inputs[:0]
+		log.Println("cleared all variables, functions and modules")
+	default:
+		return fmt.Errorf("unknown command '~%s'", name)
 	}
+	return nil
 }
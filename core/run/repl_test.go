@@ -0,0 +1,65 @@
+package run
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestHistoryEntry(t *testing.T) {
+	tests := []struct {
+		input string
+		exp   string
+	}{
+		{input: "(println 1)", exp: "(println 1)"},
+		{input: "(fun square [n]\n    (* n n))", exp: "(fun square [n] (* n n))"},
+		{input: "(fun square [n] ;; squares n\n    (* n n))", exp: "(fun square [n] (* n n))"},
+		{input: "(println \";\"\n  1)", exp: "(println \";\" 1)"},
+		{input: "(println \"a\nb\" 'c\nd')", exp: "(println \"a\\nb\" 'c\\nd')"},
+		{input: "(println \"\\\\\"\n)", exp: "(println \"\\\\\" )"},
+		{input: "(println `a\\\nb`)", exp: "(println \"a\\\\\\nb\")"},
+		{input: "(println `a`) #| a\n#| b |# |#\n(println 1)", exp: "(println `a`) #| a #| b |# |# (println 1)"},
+		{input: "(println '{(+ 1\n  2)}')", exp: "(println '{(+ 1 2)}')"},
+		{input: ";;; squares n\n(fun square [n] (* n n))", exp: "(fun square [n] (* n n))"},
+	}
+	for _, test := range tests {
+		if got := historyEntry(test.input); got != test.exp {
+			t.Errorf("wanted %q, got %q", test.exp, got)
+		}
+	}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	dir := t.TempDir()
+	lib, saved := filepath.Join(dir, "lib.phia"), filepath.Join(dir, "saved.phia")
+	if err := os.WriteFile(lib, []byte("(let b 2)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	evaluated := make([]string, 0)
+	s := &session{run: func(r io.Reader, filename string) ([]string, error) {
+		b, _ := io.ReadAll(r)
+		evaluated = append(evaluated, string(b))
+		if strings.Contains(string(b), "fail") {
+			return nil, io.EOF
+		}
+		return nil, nil
+	}}
+	s.eval("(let a 1)")
+	s.eval("(fail)")
+	s.command("load " + lib)
+	s.command("save " + saved)
+
+	if len(evaluated) != 3 || !strings.HasPrefix(evaluated[2], "(load ") {
+		t.Fatalf("expected ~load to evaluate a load statement, got %q", evaluated)
+	}
+	src, err := os.ReadFile(saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if exp := "(let a 1)\n" + evaluated[2] + "\n"; string(src) != exp {
+		t.Errorf("wanted %q, got %q", exp, src)
+	}
+}
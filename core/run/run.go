@@ -1,18 +1,25 @@
 package run
 
 import (
+	"bytes"
 	"encoding/json"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
-	"strings"
+	"os"
 
 	"github.com/xnacly/sophia/core"
 	_ "github.com/xnacly/sophia/core/builtin"
+	"github.com/xnacly/sophia/core/checker"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/cover"
 	"github.com/xnacly/sophia/core/debug"
+	"github.com/xnacly/sophia/core/debugger"
 	"github.com/xnacly/sophia/core/eval"
 	"github.com/xnacly/sophia/core/lexer"
 	"github.com/xnacly/sophia/core/parser"
+	"github.com/xnacly/sophia/core/profile"
 	"github.com/xnacly/sophia/core/serror"
 )
 
@@ -24,18 +31,21 @@ func Run(r io.Reader, filename string) (s []string, e error) {
 		}
 		if err := recover(); err != nil {
 			serror.Display()
-			if err, ok := err.(error); ok {
-				// catch all for panics
-				if !strings.Contains(err.Error(), "sophia: ") {
-					fmt.Println(err)
-				}
+			if serr, ok := err.(*serror.Error); ok {
+				e = fmt.Errorf("Runtime error found, stopped evaluation: %w", serr)
+			} else if err == debugger.ErrQuit {
+				e = debugger.ErrQuit
+			} else {
+				// catch all for panics, e.g. runtime errors or panics with
+				// non error values
+				e = fmt.Errorf("internal error: %v", err)
 			}
 			return
 		}
 	}()
 
 	debug.Log("starting lexer")
-	l := lexer.New(r)
+	l := lexer.New(r, filename)
 	tokens := l.Lex()
 	if serror.HasErrors() {
 		serror.Display()
@@ -55,6 +65,16 @@ func Run(r io.Reader, filename string) (s []string, e error) {
 		return
 	}
 
+	if core.CONF.TypeCheck {
+		debug.Log("starting type checker")
+		checker.CheckTypes(ast)
+		if serror.HasErrors() {
+			serror.Display()
+			e = errors.New("Type errors found, skipping remaining interpreter stages. (evaluation)")
+			return
+		}
+	}
+
 	if core.CONF.Debug {
 		out, _ := json.MarshalIndent(ast, "", "  ")
 		debug.Log("ast:", string(out))
@@ -62,6 +82,14 @@ func Run(r io.Reader, filename string) (s []string, e error) {
 
 	debug.Log("done parsing - starting eval")
 
+	if consts.COVER {
+		cover.Register(ast)
+	}
+	if consts.PROFILE {
+		profile.Start()
+		defer profile.Stop()
+	}
+
 	if len(ast) == 0 {
 		return
 	}
@@ -71,3 +99,50 @@ func Run(r io.Reader, filename string) (s []string, e error) {
 
 	return
 }
+
+// sophia run [-cover] [-profile] file, evaluates file like sophia file while
+// optionally recording coverage and profiling. The coverage summary and the
+// profile are written to stderr to keep the output of the program intact.
+func runCommand(args []string) int {
+	flags := flag.NewFlagSet("run", flag.ExitOnError)
+	conf := newConfFlags(flags, true)
+	coverage := newCoverFlags(flags)
+	prof := newProfileFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sophia run [-dbg] [-all-errors] [-error-format format] [-type-check] [-cover] [-cover-lcov file] [-cover-listing file] [-profile] [-profile-top n] [-profile-folded file] file")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		flags.Usage()
+		return 2
+	}
+	if err := conf.apply(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	coverage.start()
+
+	file := flags.Arg(0)
+	content, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to open file: %s\n", err)
+		return 1
+	}
+	serror.SetDefault(serror.NewFormatter(&core.CONF, string(content), file, nil))
+	code := 0
+	prof.start()
+	if _, err := Run(bytes.NewReader(content), file); err != nil {
+		fmt.Fprintln(os.Stderr, "\n"+err.Error())
+		code = 1
+	}
+	if err := prof.report(os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := coverage.report(os.Stderr); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return code
+}
@@ -0,0 +1,32 @@
+package run
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/serror"
+)
+
+func TestRunTypeCheck(t *testing.T) {
+	src := `(let a 1) (if false (println (+ a "b"))) (let done true)`
+	tests := []struct {
+		typeCheck bool
+		err       string
+	}{
+		{typeCheck: false},
+		{typeCheck: true, err: "Type errors found"},
+	}
+	for _, test := range tests {
+		conf := core.CONF
+		core.CONF.TypeCheck = test.typeCheck
+		serror.SetDefault(serror.NewFormatter(&core.CONF, src, "test", &strings.Builder{}))
+		_, err := Run(strings.NewReader(src), "test")
+		core.CONF = conf
+		if test.err == "" && err != nil {
+			t.Errorf("type-check=%t: unexpected error %v", test.typeCheck, err)
+		} else if test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)) {
+			t.Errorf("type-check=%t: wanted error %q, got %v", test.typeCheck, test.err, err)
+		}
+	}
+}
@@ -16,19 +16,30 @@ import (
 
 // entry point for sophia cli
 func Start() {
+	if len(os.Args) > 1 {
+		if command, ok := commands[os.Args[1]]; ok {
+			os.Exit(command(os.Args[2:]))
+		}
+	}
+
 	execute := flag.String("exp", "", "specifiy expression to execute")
-	dbg := flag.Bool("dbg", false, "enable debug logs")
-	allErrors := flag.Bool("all-errors", false, "display all found errors")
+	conf := newConfFlags(flag.CommandLine, true)
+	prof := newProfileFlags(flag.CommandLine)
 	flag.Parse()
-	core.CONF = core.Config{
-		Debug:     *dbg,
-		AllErrors: *allErrors,
+	if err := conf.apply(); err != nil {
+		log.Fatalln(err)
 	}
 
-	if *dbg {
-		log.SetFlags(log.Ltime | log.Lmicroseconds)
-	} else {
-		log.SetFlags(0)
+	// evaluates r, profiling it if requested. The measurements are written to
+	// stderr to keep the output of the program intact.
+	run := func(r io.Reader, filename string) ([]string, error) {
+		prof.start()
+		defer func() {
+			if err := prof.report(os.Stderr); err != nil {
+				log.Println(err)
+			}
+		}()
+		return Run(r, filename)
 	}
 
 	stdinInf, err := os.Stdin.Stat()
@@ -38,14 +49,14 @@ func Start() {
 		buf := bytes.Buffer{}
 		buf.ReadFrom(os.Stdin)
 		serror.SetDefault(serror.NewFormatter(&core.CONF, buf.String(), "stdin", nil))
-		_, err = Run(bytes.NewReader(buf.Bytes()), "stdin")
+		_, err = run(bytes.NewReader(buf.Bytes()), "stdin")
 		if err != nil {
 			log.Fatalln(err)
 		}
 	} else if len(*execute) != 0 {
 		debug.Log("got -exp flag, running...")
 		serror.SetDefault(serror.NewFormatter(&core.CONF, *execute, "cli", nil))
-		_, err := Run(strings.NewReader(*execute), "cli")
+		_, err := run(strings.NewReader(*execute), "cli")
 		if err != nil {
 			log.Fatalln(err)
 		}
@@ -61,7 +72,7 @@ func Start() {
 		r := io.TeeReader(f, buf)
 		buf.ReadFrom(r)
 		serror.SetDefault(serror.NewFormatter(&core.CONF, buf.String(), file, nil))
-		_, err = Run(buf, file)
+		_, err = run(buf, file)
 		if err != nil {
 			log.Fatalln("\n" + err.Error())
 		}
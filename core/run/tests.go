@@ -0,0 +1,186 @@
+package run
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/consts"
+	"github.com/xnacly/sophia/core/serror"
+	"github.com/xnacly/sophia/core/shared"
+)
+
+// sophia test [-run pattern] [-cover] [path ...], runs the test blocks of the given
+// files and all *_test.phia files in the given directories, defaults to the
+// current directory
+func testCommand(args []string) int {
+	flags := flag.NewFlagSet("test", flag.ExitOnError)
+	run := flags.String("run", "", "only run tests with names matching the regular expression")
+	conf := newConfFlags(flags, true)
+	coverage := newCoverFlags(flags)
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: sophia test [-run pattern] [-dbg] [-all-errors] [-error-format format] [-type-check] [-cover] [-cover-lcov file] [-cover-listing file] [path ...]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	filter, err := regexp.Compile(*run)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Invalid pattern for -run: %s\n", err)
+		return 2
+	}
+	if err := conf.apply(); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 2
+	}
+	// test files load the files next to them independent of the directory
+	// sophia test is invoked in
+	core.CONF.LoadRelative = true
+	coverage.start()
+
+	paths := flags.Args()
+	if len(paths) == 0 {
+		paths = []string{"."}
+	}
+	files, err := sourceFiles(paths, "_test.phia")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if len(files) == 0 {
+		fmt.Println("no test files found")
+		return 0
+	}
+	r := &testRunner{w: os.Stdout, filter: filter}
+	for _, file := range files {
+		r.runFile(file)
+	}
+	code := r.summary()
+	if err := coverage.report(os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return code
+}
+
+// runs test files and reports their results
+type testRunner struct {
+	w      io.Writer
+	filter *regexp.Regexp
+	passed int
+	failed int
+	// test files whose top level could not be evaluated
+	broken int
+}
+
+// evaluates the top level of file, collecting its test blocks, and runs each
+// test whose name matches the filter. Every test starts with the variables,
+// functions and modules defined by the top level, changes made by a test
+// are not visible to the following tests. The tables are copied shallowly:
+// arrays and objects are shared between tests, which is sufficient as long as
+// sophia provides no way to modify them in place, see expr.Var.
+func (r *testRunner) runFile(file string) {
+	content, err := os.ReadFile(file)
+	if err != nil {
+		fmt.Fprintf(r.w, "FAIL\t%s\n\tFailed to open file: %s\n", file, err)
+		r.broken++
+		return
+	}
+	reset()
+	consts.TESTS = consts.TESTS[:0]
+	consts.COLLECT_TESTS = true
+	serror.SetDefault(serror.NewFormatter(&core.CONF, string(content), file, r.w))
+	_, err = Run(bytes.NewReader(content), file)
+	consts.COLLECT_TESTS = false
+	if err != nil {
+		fmt.Fprintf(r.w, "FAIL\t%s\n\t%s\n", file, err)
+		r.broken++
+		return
+	}
+
+	symbols, scope := maps.Clone(consts.SYMBOL_TABLE), maps.Clone(consts.SCOPE_TABLE)
+	functions, modules := maps.Clone(consts.FUNC_TABLE), maps.Clone(consts.MODULE_TABLE)
+	passed, failed := 0, 0
+	for _, test := range consts.TESTS {
+		if !r.filter.MatchString(test.Name) {
+			continue
+		}
+		restore(consts.SYMBOL_TABLE, symbols)
+		restore(consts.SCOPE_TABLE, scope)
+		restore(consts.FUNC_TABLE, functions)
+		restore(consts.MODULE_TABLE, modules)
+		consts.RETURN = consts.Return{}
+		consts.CALL_STACK = consts.CALL_STACK[:0]
+		serror.Clear()
+
+		start := time.Now()
+		err := runTest(test)
+		took := time.Since(start).Round(time.Microsecond)
+		if err == nil {
+			fmt.Fprintf(r.w, "--- PASS: %s (%s)\n", test.Name, took)
+			passed++
+			continue
+		}
+		fmt.Fprintf(r.w, "--- FAIL: %s (%s)\n", test.Name, took)
+		if serror.HasErrors() {
+			serror.Display()
+			fmt.Fprintln(r.w)
+		} else {
+			fmt.Fprintf(r.w, "\t%s\n", err)
+		}
+		failed++
+	}
+	r.passed += passed
+	r.failed += failed
+	if failed != 0 {
+		fmt.Fprintf(r.w, "FAIL\t%s\t%d of %d tests failed\n", file, failed, passed+failed)
+	} else {
+		fmt.Fprintf(r.w, "ok\t%s\t%d tests passed\n", file, passed)
+	}
+}
+
+// replaces the contents of table with the contents of snapshot
+func restore[K comparable](table map[K]any, snapshot map[K]any) {
+	clear(table)
+	maps.Copy(table, snapshot)
+}
+
+// evaluates the body of test, returns the error stopping its evaluation.
+// The body is evaluated in a frame named after the test, so stack traces
+// point at the test instead of the top level.
+func runTest(test consts.Test) (err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			if serr, ok := e.(*serror.Error); ok {
+				err = serr
+			} else {
+				err = fmt.Errorf("%v", e)
+			}
+		}
+	}()
+	consts.CALL_STACK = append(consts.CALL_STACK, consts.Frame{Name: "test " + shared.Repr(test.Name), Call: test.Token})
+	for _, n := range test.Body {
+		n.Eval()
+	}
+	return nil
+}
+
+// writes the total results of all test files, returns the exit code: 1 if a
+// test failed or a test file could not be evaluated
+func (r *testRunner) summary() int {
+	if r.failed == 0 && r.broken == 0 {
+		fmt.Fprintf(r.w, "ok\t%d passed\n", r.passed)
+		return 0
+	}
+	fmt.Fprintf(r.w, "FAIL\t%d passed, %d failed", r.passed, r.failed)
+	if r.broken != 0 {
+		fmt.Fprintf(r.w, ", %d files could not be evaluated", r.broken)
+	}
+	fmt.Fprintln(r.w)
+	return 1
+}
@@ -0,0 +1,91 @@
+package run
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+)
+
+func TestRunTests(t *testing.T) {
+	dir := t.TempDir()
+	math, broken := filepath.Join(dir, "math_test.phia"), filepath.Join(dir, "broken_test.phia")
+	sources := map[string]string{
+		math: `(let counter 0)
+(test "isolated" (let counter 5) (assert (= counter 5)))
+(test "starts at zero" (assert (= counter 0)))
+(test "fails" (assert (= (+ 1 1) 3)))
+`,
+		broken:                          `(assert false)`,
+		filepath.Join(dir, "math.phia"): `(assert false)`,
+	}
+	for file, src := range sources {
+		if err := os.WriteFile(file, []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	files, err := sourceFiles([]string{dir}, "_test.phia")
+	if err != nil || len(files) != 2 {
+		t.Fatalf("wanted 2 test files, got %v, %v", files, err)
+	}
+
+	tests := []struct {
+		filter string
+		code   int
+		passed int
+		failed int
+		output []string
+	}{
+		{filter: "", code: 1, passed: 2, failed: 1, output: []string{
+			"--- PASS: isolated", "--- PASS: starts at zero", "--- FAIL: fails",
+			"Assertion failed, expected 3, got 2", "FAIL\t" + math + "\t1 of 3 tests failed", "FAIL\t" + broken,
+		}},
+		{filter: "zero", code: 1, passed: 1, output: []string{"--- PASS: starts at zero", "ok\t" + math}},
+	}
+	for _, test := range tests {
+		b := &strings.Builder{}
+		r := &testRunner{w: b, filter: regexp.MustCompile(test.filter)}
+		for _, file := range files {
+			r.runFile(file)
+		}
+		if code := r.summary(); code != test.code || r.passed != test.passed || r.failed != test.failed || r.broken != 1 {
+			t.Errorf("wanted exit code %d with %d passed and %d failed, got %d with %d passed, %d failed and %d broken", test.code, test.passed, test.failed, code, r.passed, r.failed, r.broken)
+		}
+		for _, o := range test.output {
+			if !strings.Contains(b.String(), o) {
+				t.Errorf("wanted %q in output, got:\n%s", o, b.String())
+			}
+		}
+	}
+}
+
+func TestRunTestsLoad(t *testing.T) {
+	dir := t.TempDir()
+	sources := map[string]string{
+		"lib.phia": `(fun square [n] (* n n))`,
+		"lib_test.phia": `(load "lib.phia")
+(test "square" (assert (= (square 3) 9)))
+(test "trace" (assert false))
+`,
+	}
+	for file, src := range sources {
+		if err := os.WriteFile(filepath.Join(dir, file), []byte(src), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	core.CONF.LoadRelative = true
+	defer func() { core.CONF.LoadRelative = false }()
+
+	b := &strings.Builder{}
+	r := &testRunner{w: b, filter: regexp.MustCompile("")}
+	r.runFile(filepath.Join(dir, "lib_test.phia"))
+	if r.passed != 1 || r.failed != 1 || r.broken != 0 {
+		t.Fatalf("wanted 1 passed and 1 failed, got %d passed, %d failed and %d broken:\n%s", r.passed, r.failed, r.broken, b.String())
+	}
+	if !strings.Contains(b.String(), `at test "trace" in`) {
+		t.Errorf("wanted the failing test in the stack trace, got:\n%s", b.String())
+	}
+}
@@ -0,0 +1,247 @@
+package serror
+
+import "strings"
+
+type Severity int
+
+const (
+	ERROR Severity = iota
+	WARNING
+	NOTE
+)
+
+func (s Severity) String() string {
+	switch s {
+	case WARNING:
+		return "warning"
+	case NOTE:
+		return "note"
+	default:
+		return "error"
+	}
+}
+
+func (s Severity) color() string {
+	switch s {
+	case WARNING:
+		return ANSI_YELLOW
+	case NOTE:
+		return ANSI_BLUE
+	default:
+		return ANSI_RED
+	}
+}
+
+// stable identifier of a kind of error, explained via sophia explain <code>
+type Code struct {
+	Code     string
+	Title    string
+	Severity Severity
+	// long form explanation, including an example causing the error
+	Explanation string
+}
+
+// all known codes, errors are assigned the code matching their title. Codes
+// must never be changed or reused, append new codes at the end.
+var CODES = []Code{
+	{Code: "S0001", Title: "Unknown character", Explanation: `The lexer encountered a character that is not part of the sophia syntax.
+
+    (let a 1 $)
+
+Remove the character or, if it is meant to be text, place it in a string.`},
+	{Code: "S0002", Title: "Unterminated string", Explanation: `A string was opened with " but never closed.
+
+    (println "hello)
+
+Add the closing double quote.`},
+	{Code: "S0003", Title: "Unterminated raw string", Explanation: "A raw string was opened with ` but never closed.\n\n    (println `hello)\n\nAdd the closing backtick."},
+	{Code: "S0004", Title: "Unterminated template string", Explanation: `A template string was opened with ' but never closed.
+
+    (println 'hello {name})
+
+Add the closing single quote.`},
+	{Code: "S0005", Title: "Unterminated interpolation in template string", Explanation: `An interpolation inside a template string was opened with { but never closed.
+
+    (println 'hello {name')
+
+Add the closing curly brace.`},
+	{Code: "S0006", Title: "Empty interpolation in template string", Explanation: `An interpolation inside a template string does not contain an expression.
+
+    (println 'hello {}')
+
+Add an expression, or double the braces to write them literally: '{{}}'.`},
+	{Code: "S0007", Title: "Unmatched '}' in template string", Explanation: `A template string contains a closing curly brace without an opening one.
+
+    (println 'a } b')
+
+Double the brace to write it literally: 'a }} b'.`},
+	{Code: "S0008", Title: "Unterminated block comment", Explanation: `A block comment was opened with #| but never closed.
+
+    #| a comment
+
+Close the comment with |#, nested block comments have to be closed as well.`},
+	{Code: "S0009", Title: "Invalid escape sequence", Explanation: `A string contains a backslash followed by a character that is not a known escape sequence.
+
+    (println "\q")
+
+Known escape sequences are \", \', \\, \n, \t, \r and \u{...}, escape the backslash to write it literally: "\\q".`},
+	{Code: "S0010", Title: "Invalid numeric literal", Explanation: `A number is malformed, for instance by containing digits not valid for its base or misplaced underscores.
+
+    (println 0b102 1__000)
+
+Underscores are only allowed between digits.`},
+	{Code: "S0011", Title: "Unexpected end of file", Explanation: `The source to execute is empty.`},
+	{Code: "S0012", Title: "Unexpected end of input", Explanation: `The parser did not receive any token, the source to execute is possibly empty.`},
+	{Code: "S0013", Title: "Unexpected Token", Explanation: `The parser found a token not allowed at this position, such as a missing closing brace or an unknown operator.
+
+    (println 1
+
+Add the missing token or remove the unexpected one.`},
+	{Code: "S0014", Title: "Failed to parse number", Explanation: `A number could not be converted to a floating point number or decimal.
+
+    (let a 1e999999)`},
+	{Code: "S0015", Title: "Invalid format specifier", Explanation: `A format specifier in a template string is malformed.
+
+    (println '{pi:.x}')
+
+Format specifiers consist of an optional alignment, width, precision and type, such as {pi:>8.2f}.`},
+	{Code: "S0016", Title: "Unknown type", Explanation: `A type annotation names a type that does not exist.
+
+    (let a:int 1)
+
+Known types are any, float, decimal, string, bool, array and object.`},
+	{Code: "S0017", Title: "Not enough arguments", Explanation: `A function or statement was called with fewer arguments than it requires.
+
+    (fun square [n] (* n n))
+    (square)
+
+Pass an argument for each parameter.`},
+	{Code: "S0018", Title: "Too many arguments", Explanation: `A function or statement was called with more arguments than it accepts.
+
+    (fun square [n] (* n n))
+    (square 1 2)
+
+Remove the surplus arguments.`},
+	{Code: "S0019", Title: "Not enough parameters", Explanation: `A definition is missing required parts, such as the parameters of a function or the name of a module.
+
+    (fun square)
+
+Functions require a name and a list of parameters: (fun square [n] (* n n)).`},
+	{Code: "S0020", Title: "Incorrect parameter amount", Explanation: `An operator was given the wrong amount of operands.
+
+    (+ 1)
+    (not true false)
+
+Arithmetic operators require at least two operands, not accepts exactly one.`},
+	{Code: "S0021", Title: "Parameter error", Explanation: `A variable definition does not start with an identifier.
+
+    (let 1 2)`},
+	{Code: "S0022", Title: "Failed to source import", Explanation: `A file loaded via load could not be opened.
+
+    (load "missing.phia")
+
+Paths are resolved relative to the working directory.`},
+	{Code: "S0023", Title: "Detected recursion in file imports", Explanation: `A file loads itself, directly or via the files it loads, which would never terminate.
+
+    ;; main.phia
+    (load "main.phia")`},
+	{Code: "S0024", Title: "Undefined variable", Explanation: `A variable is used but never defined.
+
+    (println name)
+
+Define the variable via let before using it: (let name "anon").`},
+	{Code: "S0025", Title: "Undefined function", Explanation: `A function is called but never defined.
+
+    (greet "anon")
+
+Define the function via fun or check the name for typos.`},
+	{Code: "S0026", Title: "Undefined module", Explanation: `A module is used but never defined.
+
+    (use person)
+
+Define the module via module before using it.`},
+	{Code: "S0027", Title: "Type error", Explanation: `A value of the wrong type was used, for instance adding a string to a number or a value not matching its type annotation.
+
+    (+ 1 "2")
+    (let a:float "a")`},
+	{Code: "S0028", Title: "Argument error", Explanation: `A built-in function was called with the wrong amount or kind of arguments.
+
+    (len 1 2)`},
+	{Code: "S0029", Title: "Index error", Explanation: `A value not indexable was indexed, or an index of the wrong type was used.
+
+    (let a 1)
+    (println a#[0])
+
+Arrays are indexed with numbers, objects are indexed with keys.`},
+	{Code: "S0030", Title: "Out of bounds error", Explanation: `An index is larger than the array indexed.
+
+    (let a [1 2])
+    (println a#[5])`},
+	{Code: "S0031", Title: "Invalid iterator", Explanation: `A loop iterates over a value that is neither an array nor a number.
+
+    (for [i] "abc" (println i))`},
+	{Code: "S0032", Title: "Illegal object key", Explanation: `An object key is not an identifier.
+
+    (let a {1: 2})`},
+	{Code: "S0033", Title: "Illogical lambda", Explanation: `A lambda was evaluated without arguments, lambdas are meant to be passed to built-ins such as map and filter.
+
+    (lambda [a] (* a 2))
+
+Pass the lambda to a built-in: (map (lambda [a] (* a 2)) [1 2 3]).`},
+	{Code: "S0034", Title: "Division by zero", Explanation: `A number was divided by zero.
+
+    (/ 1 0)`},
+	{Code: "S0035", Title: "Format error", Explanation: `A value can not be formatted using the format specifier given in a template string.
+
+    (println '{"a":.2f}')`},
+	{Code: "S0036", Title: "Assertion error", Explanation: `An assertion failed: the condition passed to assert is not true.
+
+    (assert (= 1 2))`},
+	{Code: "S0037", Title: "Not implemented", Explanation: `The feature used is not yet implemented by the interpreter.`},
+	{Code: "S0038", Title: "Unexpected type annotation", Explanation: `A type annotation was used at a position not accepting one. Only variable definitions, function names and function parameters can be annotated.
+
+    (println a:float)`},
+	{Code: "S0039", Title: "Unbalanced braces", Explanation: `A brace was opened but never closed, or closed without being opened.
+
+    (println (+ 1 2)`},
+	{Code: "S0040", Title: "Unused variable", Severity: WARNING, Explanation: `A variable is defined but never used.
+
+    (let unused 1)
+
+Remove the variable or use it.`},
+	{Code: "S0041", Title: "Shadowed variable", Severity: WARNING, Explanation: `A variable is defined with the same name as a variable of an enclosing scope, the outer variable is not accessible while the inner one is defined.
+
+    (let a 1)
+    (for [a] 5 (println a))`},
+	{Code: "S0042", Title: "Unreachable code", Severity: WARNING, Explanation: `Statements following a return statement are never executed.
+
+    (fun f []
+        (return 1)
+        (println "never"))`},
+	{Code: "S0043", Title: "Unattached doc comment", Severity: WARNING, Explanation: `A ;;; doc comment is not directly followed by a fun, module or let definition and therefore documents nothing.
+
+    ;;; prints the greeting
+    (println "hello")
+
+Move the comment in front of a definition or use ;; for a regular comment.`},
+}
+
+// returns the code for the title of an error, the zero value if there is none
+func codeOf(title string) Code {
+	for _, c := range CODES {
+		if c.Title == title {
+			return c
+		}
+	}
+	return Code{}
+}
+
+// returns the code identified by code, such as S0024, case insensitive
+func LookupCode(code string) (Code, bool) {
+	for _, c := range CODES {
+		if strings.EqualFold(c.Code, code) {
+			return c, true
+		}
+	}
+	return Code{}, false
+}
@@ -7,19 +7,37 @@ import (
 	"strings"
 
 	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/consts"
 	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
 )
 
 var defaultFormatter *ErrorFormatter
 
-func Add(t *token.Token, title string, info string, additional ...any) {
-	defaultFormatter.Add(t, title, info, additional...)
+func Add(t *token.Token, title string, info string, additional ...any) *Error {
+	return defaultFormatter.Add(t, title, info, additional...)
+}
+
+func AddNode(n types.Node, title string, info string, additional ...any) *Error {
+	return defaultFormatter.AddNode(n, title, info, additional...)
+}
+
+func AddSource(file string, src string, from *token.Token) {
+	defaultFormatter.AddSource(file, src, from)
+}
+
+func Text(span token.Span) string {
+	return defaultFormatter.Text(span)
 }
 
 func Display() {
 	defaultFormatter.Display()
 }
 
+func Clear() {
+	defaultFormatter.Clear()
+}
+
 func HasErrors() bool {
 	return defaultFormatter.HasErrors()
 }
@@ -32,9 +50,38 @@ func Default() *ErrorFormatter {
 	return defaultFormatter
 }
 
+// attaches the call stack to the last error and stops the evaluation by
+// panicking with the error, recovered in run.Run
 func Panic() {
 	err := defaultFormatter.errors[len(defaultFormatter.errors)-1]
-	panic("sophia: " + err.Title + ": " + err.Info)
+	err.Trace = trace(err.Span)
+	panic(err)
+}
+
+// builds the stack trace for a runtime error at span from the call stack,
+// the innermost frame first
+func trace(span token.Span) []Frame {
+	t := make([]Frame, 0, len(consts.CALL_STACK)+1)
+	for i := len(consts.CALL_STACK) - 1; i >= 0; i-- {
+		f := consts.CALL_STACK[i]
+		t = append(t, Frame{Name: f.Name, Span: span, Builtin: f.Builtin})
+		span = f.Call.Span()
+	}
+	return append(t, Frame{Name: TOP_LEVEL, Span: span})
+}
+
+// Colored reports whether output written to w should be colored: w has to be
+// a terminal and NO_COLOR (https://no-color.org) must not be set
+func Colored(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
 }
 
 func NewFormatter(config *core.Config, input string, filename string, w io.Writer) *ErrorFormatter {
@@ -42,10 +89,11 @@ func NewFormatter(config *core.Config, input string, filename string, w io.Write
 		w = os.Stdout
 	}
 	return &ErrorFormatter{
-		conf:   config,
-		lines:  strings.Split(input, "\n"),
-		file:   filename,
-		w:      bufio.NewWriter(w),
-		errors: make([]Error, 0),
+		conf:    config,
+		sources: map[string]*source{filename: {lines: strings.Split(input, "\n")}},
+		file:    filename,
+		w:       bufio.NewWriter(w),
+		color:   Colored(w),
+		errors:  make([]*Error, 0),
 	}
 }
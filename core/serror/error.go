@@ -7,13 +7,32 @@ import (
 	"fmt"
 	"path/filepath"
 	"strconv"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/xnacly/sophia/core"
 	"github.com/xnacly/sophia/core/token"
+	"github.com/xnacly/sophia/core/types"
 )
 
 const MAX_ERRORS = 3
 
+// formats errors can be displayed in, see core.Config.ErrorFormat
+const (
+	FORMAT_TEXT  = "text"
+	FORMAT_JSON  = "json"
+	FORMAT_SARIF = "sarif"
+)
+
+// reports whether errors can be displayed in format, empty defaults to text
+func KnownFormat(format string) bool {
+	switch format {
+	case "", FORMAT_TEXT, FORMAT_JSON, FORMAT_SARIF:
+		return true
+	}
+	return false
+}
+
 const (
 	ANSI_RESET   = "\033[0m"
 	ANSI_RED     = "\033[91m"
@@ -23,30 +42,157 @@ const (
 )
 
 type ErrorFormatter struct {
-	conf   *core.Config
-	lines  []string
-	errors []Error
-	w      *bufio.Writer
-	file   string
+	conf *core.Config
+	// source code of the file executed and all files loaded by it, errors
+	// are displayed against the file they occurred in
+	sources map[string]*source
+	errors  []*Error
+	w       *bufio.Writer
+	file    string
+	// write ansi escape codes, disabled if not writing to a terminal
+	color bool
+}
+
+type source struct {
+	lines []string
+	// string of the load statement importing the file, nil for the file
+	// executed
+	from *token.Token
+}
+
+// registers the source code of file, loaded by the load statement argument
+// from. Files loaded multiple times keep their first import.
+func (e *ErrorFormatter) AddSource(file string, src string, from *token.Token) {
+	if _, ok := e.sources[file]; ok {
+		return
+	}
+	e.sources[file] = &source{lines: strings.Split(src, "\n"), from: from}
+}
+
+// returns the lines of file, errors without a file belong to the file
+// executed. Returns nil for files not registered via AddSource.
+func (e *ErrorFormatter) lines(file string) []string {
+	if file == "" {
+		file = e.file
+	}
+	if s, ok := e.sources[file]; ok {
+		return s.lines
+	}
+	return nil
+}
+
+// returns the source code span refers to, empty if its file is unknown
+func (e *ErrorFormatter) Text(span token.Span) string {
+	src := strings.Join(e.lines(span.File), "\n")
+	if span.Start.Offset < 0 || span.End.Offset > len(src) || span.Start.Offset > span.End.Offset {
+		return ""
+	}
+	return src[span.Start.Offset:span.End.Offset]
+}
+
+// returns the load statement arguments leading to file, starting with the
+// load statement importing file, empty for the file executed
+func (e *ErrorFormatter) ImportedFrom(file string) []*token.Token {
+	chain := make([]*token.Token, 0)
+	seen := map[string]bool{}
+	for !seen[file] {
+		seen[file] = true
+		s, ok := e.sources[file]
+		if !ok || s.from == nil {
+			break
+		}
+		chain = append(chain, s.from)
+		file = s.from.File
+	}
+	return chain
 }
 
+// returns the file the error occurred in, the file executed if unknown
+func (e *ErrorFormatter) fileOf(span token.Span) string {
+	if span.File == "" {
+		return e.file
+	}
+	return span.File
+}
+
+// returns the absolute path of file, pseudo files such as the repl are
+// returned as is
+func path(file string) string {
+	if file == "cli" || file == "repl" || file == "stdin" || file == "" {
+		return file
+	}
+	// only errors on os.Getwd (we don't really care)
+	p, err := filepath.Abs(file)
+	if err != nil {
+		return file
+	}
+	return p
+}
+
+// reports whether errors were added, warnings and notes are not errors
 func (e *ErrorFormatter) HasErrors() bool {
-	return len(e.errors) > 0
+	for _, err := range e.errors {
+		if err.Severity == ERROR {
+			return true
+		}
+	}
+	return false
 }
 
-func (e *ErrorFormatter) Add(t *token.Token, title string, info string, additional ...any) {
-	e.errors = append(e.errors, Error{t, title, fmt.Sprintf(info, additional...)})
+// removes all added errors, the registered sources are kept
+func (e *ErrorFormatter) Clear() {
+	e.errors = e.errors[:0]
+}
+
+// returns all errors, warnings and notes added to the formatter
+func (e *ErrorFormatter) Errors() []*Error {
+	return e.errors
+}
+
+// adds an error, its code and severity are looked up via its title. The
+// error can be extended with labels and suggestions.
+func (e *ErrorFormatter) Add(t *token.Token, title string, info string, additional ...any) *Error {
+	return e.add(t, t.Span(), title, fmt.Sprintf(info, additional...))
+}
+
+// adds an error spanning the whole source code of the node n instead of only
+// its token
+func (e *ErrorFormatter) AddNode(n types.Node, title string, info string, additional ...any) *Error {
+	return e.add(n.GetToken(), n.GetSpan(), title, fmt.Sprintf(info, additional...))
+}
+
+// errors equal to the last error are not added again, such as the missing
+// closing braces of nested statements all reported at the end of the input
+func (e *ErrorFormatter) add(t *token.Token, span token.Span, title string, info string) *Error {
+	if n := len(e.errors); n != 0 {
+		if last := e.errors[n-1]; last.Title == title && last.Info == info && last.Span == span {
+			return last
+		}
+	}
+	code := codeOf(title)
+	err := &Error{
+		Token:    t,
+		Span:     span,
+		Title:    title,
+		Info:     info,
+		Code:     code.Code,
+		Severity: code.Severity,
+	}
+	e.errors = append(e.errors, err)
+	return err
 }
 
 func (e *ErrorFormatter) Display() {
 	if len(e.errors) == 0 {
 		return
 	}
-	// if a file, lookup absolute path
-	if e.file != "cli" && e.file != "repl" && e.file != "stdin" {
-		// only errors on os.Getwd (we don't really care)
-		path, _ := filepath.Abs(e.file)
-		e.file = path
+	switch e.conf.ErrorFormat {
+	case FORMAT_JSON:
+		e.json()
+		return
+	case FORMAT_SARIF:
+		e.sarif()
+		return
 	}
 	for i, err := range e.errors {
 		if i < MAX_ERRORS || e.conf.AllErrors {
@@ -55,55 +201,130 @@ func (e *ErrorFormatter) Display() {
 				e.w.WriteRune('\n')
 			}
 		} else {
-			fmt.Fprintf(e.w, "Too many errors, skipping the remaining %d, rerun with '-all-errors' to view all %d errors", len(e.errors)-MAX_ERRORS, len(e.errors))
+			fmt.Fprintf(e.w, "Too many errors, skipping the remaining %d, rerun with '-all-errors' to view all %d errors\n", len(e.errors)-MAX_ERRORS, len(e.errors))
 			break
 		}
 	}
 	e.w.Flush()
 }
 
+// writes the ansi escape code if colors are enabled
+func (e *ErrorFormatter) ansi(code string) {
+	if e.color {
+		e.w.WriteString(code)
+	}
+}
+
 type Error struct {
-	Token *token.Token
-	Title string // smth like Unknown token
-	Info  string // in depth information: expected 'x' got 'y'
+	Token    *token.Token
+	Span     token.Span // source code the error refers to, underlined in the snippet
+	Title    string     // smth like Unknown token
+	Info     string     // in depth information: expected 'x' got 'y'
+	Code     string     // stable identifier, such as S0024, empty if unknown
+	Severity Severity
+	// source code related to the error, such as the definition of a
+	// function called with the wrong amount of arguments
+	Labels []Label
+	Help   string // suggestion for fixing the error
+	// functions evaluated when a runtime error occurred, the innermost
+	// first, nil for errors found before evaluation
+	Trace []Frame
+}
+
+func (e *Error) Error() string {
+	return e.Title + ": " + e.Info
+}
+
+// name of the frame evaluating the statements outside of functions
+const TOP_LEVEL = "<top level>"
+
+// maximum amount of frames displayed, the frames in between the innermost and
+// outermost frames are omitted for deeply nested calls
+const MAX_FRAMES = 16
+
+// function evaluated when a runtime error occurred
+type Frame struct {
+	Name string
+	// source code evaluated by the function, the call of the next
+	// frame or the error itself
+	Span    token.Span
+	Builtin bool
+}
+
+// secondary location of an error
+type Label struct {
+	Span    token.Span
+	Message string
+}
+
+// adds a label to the error pointing at span
+func (e *Error) Label(span token.Span, message string, additional ...any) *Error {
+	e.Labels = append(e.Labels, Label{Span: span, Message: fmt.Sprintf(message, additional...)})
+	return e
+}
+
+// suggests the candidate most similar to name, if any is similar enough:
+// did you mean `println`?
+func (e *Error) Suggest(name string, candidates []string) *Error {
+	if s, ok := Closest(name, candidates); ok {
+		e.Help = fmt.Sprintf("did you mean `%s`?", s)
+	}
+	return e
 }
 
 // responsible for formatting the error title and the  filename + line + pos
 func (e *Error) title(errFmt *ErrorFormatter) {
-	errFmt.w.WriteString(ANSI_RED)
-	errFmt.w.WriteString("error: ")
-	errFmt.w.WriteString(ANSI_RESET)
+	errFmt.ansi(e.Severity.color())
+	errFmt.w.WriteString(e.Severity.String())
+	if e.Code != "" {
+		errFmt.w.WriteString("[" + e.Code + "]")
+	}
+	errFmt.w.WriteString(": ")
+	errFmt.ansi(ANSI_RESET)
 	errFmt.w.WriteString(e.Title)
 	errFmt.w.WriteString("\n\n\tat: ")
-	errFmt.w.WriteString(ANSI_BLUE)
-	errFmt.w.WriteString(errFmt.file)
-	errFmt.w.WriteString(ANSI_RESET)
-	errFmt.w.WriteRune(':')
-	errFmt.w.WriteString(strconv.Itoa(e.Token.Line + 1))
-	errFmt.w.WriteRune(':')
-	errFmt.w.WriteString(strconv.Itoa(e.Token.LinePos + 1))
+	errFmt.location(errFmt.fileOf(e.Span), e.Span.Start)
 	errFmt.w.WriteRune(':')
+	for _, t := range errFmt.ImportedFrom(errFmt.fileOf(e.Span)) {
+		errFmt.w.WriteString("\n\timported from: ")
+		errFmt.location(t.File, t.Span().Start)
+	}
+}
+
+// writes the absolute path of file, followed by the line and column of pos
+func (e *ErrorFormatter) location(file string, pos token.Position) {
+	e.ansi(ANSI_BLUE)
+	e.w.WriteString(path(file))
+	e.ansi(ANSI_RESET)
+	e.w.WriteRune(':')
+	e.w.WriteString(strconv.Itoa(pos.Line + 1))
+	e.w.WriteRune(':')
+	e.w.WriteString(strconv.Itoa(pos.Column + 1))
 }
 
 // responsible for formatting the code snippet
 func (e *Error) snippet(errFmt *ErrorFormatter) {
-	t := e.Token
+	lines := errFmt.lines(e.Span.File)
+	lineNum := e.Span.Start.Line
+	if lineNum >= len(lines) {
+		return
+	}
 	prevLineAmount := 2
 	nextLineAmount := 2
-	if len(errFmt.lines) == 1 {
+	if len(lines) == 1 {
 		prevLineAmount = 0
 		nextLineAmount = 0
 	}
 
-	if t.Line == 0 {
+	if lineNum == 0 {
 		prevLineAmount = 0
 	}
-	lineIndex := t.Line - prevLineAmount
+	lineIndex := lineNum - prevLineAmount
 	if lineIndex < 0 {
 		lineIndex = 0
 	}
 
-	prevLines := errFmt.lines[lineIndex:t.Line]
+	prevLines := lines[lineIndex:lineNum]
 
 	for _, line := range prevLines {
 		e.line(errFmt, line, lineIndex)
@@ -111,19 +332,19 @@ func (e *Error) snippet(errFmt *ErrorFormatter) {
 	}
 
 	// print the offending line
-	e.error(errFmt, errFmt.lines[t.Line])
+	e.error(errFmt, lines[lineNum])
 	lineIndex++
 
-	nextLineAmount = t.Line + 1 + nextLineAmount
-	if nextLineAmount >= len(errFmt.lines)-1 {
-		nextLineAmount = len(errFmt.lines) - 1
+	nextLineAmount = lineNum + 1 + nextLineAmount
+	if nextLineAmount >= len(lines)-1 {
+		nextLineAmount = len(lines) - 1
 	}
-	baseLine := t.Line + 1
-	if baseLine >= len(errFmt.lines)-1 {
-		baseLine = len(errFmt.lines) - 1
+	baseLine := lineNum + 1
+	if baseLine >= len(lines)-1 {
+		baseLine = len(lines) - 1
 	}
 
-	nextLines := errFmt.lines[baseLine:nextLineAmount]
+	nextLines := lines[baseLine:nextLineAmount]
 
 	for _, line := range nextLines {
 		e.line(errFmt, line, lineIndex)
@@ -133,13 +354,66 @@ func (e *Error) snippet(errFmt *ErrorFormatter) {
 
 // formats the error line
 func (e *Error) error(errFmt *ErrorFormatter, line string) {
-	e.line(errFmt, line, e.Token.Line)
+	e.line(errFmt, line, e.Span.Start.Line)
+	e.underline(errFmt, line, e.Span.Start.Column, e.Severity.color(), '^', e.width(len([]rune(line))))
+	errFmt.ansi(ANSI_RESET)
+}
+
+// formats the labels of the error, each label is displayed below the line
+// it points at
+func (e *Error) labels(errFmt *ErrorFormatter) {
+	for _, l := range e.Labels {
+		lines := errFmt.lines(l.Span.File)
+		if l.Span.Start.Line >= len(lines) {
+			continue
+		}
+		// labels pointing into other files are introduced by their location
+		if file := errFmt.fileOf(l.Span); file != errFmt.fileOf(e.Span) {
+			errFmt.w.WriteString("\n\tin: ")
+			errFmt.location(file, l.Span.Start)
+			errFmt.w.WriteRune(':')
+		}
+		line := lines[l.Span.Start.Line]
+		e.line(errFmt, line, l.Span.Start.Line)
+		e.underline(errFmt, line, l.Span.Start.Column, ANSI_BLUE, '-', (&Error{Span: l.Span}).width(len([]rune(line))))
+		errFmt.w.WriteRune(' ')
+		errFmt.w.WriteString(l.Message)
+		errFmt.ansi(ANSI_RESET)
+	}
+}
+
+// writes r n times in the given color below line, starting at column
+func (e *Error) underline(errFmt *ErrorFormatter, line string, column int, color string, r rune, n int) {
 	errFmt.w.WriteString("\n\t")
 	fmt.Fprintf(errFmt.w, "%5s| ", " ")
-	runeRepeat(errFmt.w, ' ', e.Token.LinePos-1)
-	errFmt.w.WriteString(ANSI_RED)
-	runeRepeat(errFmt.w, '^', len(e.Token.Raw))
-	errFmt.w.WriteString(ANSI_RESET)
+	// keep tabs of the line in front of the error to align the underline
+	runes := []rune(line)
+	for i := 0; i < column && i < len(runes); i++ {
+		if runes[i] == '\t' {
+			errFmt.w.WriteRune('\t')
+		} else {
+			errFmt.w.WriteRune(' ')
+		}
+	}
+	errFmt.ansi(color)
+	runeRepeat(errFmt.w, r, n)
+}
+
+// computes the amount of characters to underline in the first line of the
+// span, spans reaching into the following lines are underlined until the end
+// of the line
+func (e *Error) width(lineLength int) int {
+	start, end := e.Span.Start, e.Span.End
+	n := 0
+	if end.Line == start.Line {
+		n = end.Column - start.Column
+	} else if end.Line > start.Line {
+		n = lineLength - start.Column
+	} else if e.Token != nil {
+		// no span available, fall back to the raw token
+		n = utf8.RuneCountInString(e.Token.Raw)
+	}
+	return max(n, 1)
 }
 
 // formats a singular line
@@ -153,10 +427,49 @@ func (e *Error) prettyPrint(errFmt *ErrorFormatter) {
 	e.title(errFmt)
 	errFmt.w.WriteRune('\n')
 	e.snippet(errFmt)
+	if len(e.Labels) != 0 {
+		errFmt.w.WriteRune('\n')
+		e.labels(errFmt)
+	}
 	errFmt.w.WriteRune('\n')
 	errFmt.w.WriteRune('\n')
 	errFmt.w.WriteString(e.Info)
 	errFmt.w.WriteRune('\n')
+	if e.Help != "" {
+		errFmt.ansi(ANSI_MAGENTA)
+		errFmt.w.WriteString("help: ")
+		errFmt.ansi(ANSI_RESET)
+		errFmt.w.WriteString(e.Help)
+		errFmt.w.WriteRune('\n')
+	}
+	// only display traces for errors inside of functions
+	if len(e.Trace) > 1 {
+		e.trace(errFmt)
+	}
+	if e.Code != "" {
+		fmt.Fprintf(errFmt.w, "\nrun 'sophia explain %s' for more information\n", e.Code)
+	}
+}
+
+// formats the stack trace, a frame per line
+func (e *Error) trace(errFmt *ErrorFormatter) {
+	errFmt.w.WriteString("\nstack trace (most recent call first):\n")
+	for i, f := range e.Trace {
+		if len(e.Trace) > MAX_FRAMES && i == MAX_FRAMES/2 {
+			fmt.Fprintf(errFmt.w, "\t... %d frames omitted ...\n", len(e.Trace)-MAX_FRAMES)
+		}
+		if len(e.Trace) > MAX_FRAMES && i >= MAX_FRAMES/2 && i < len(e.Trace)-MAX_FRAMES/2 {
+			continue
+		}
+		errFmt.w.WriteString("\tat ")
+		errFmt.w.WriteString(f.Name)
+		if f.Builtin {
+			errFmt.w.WriteString(" (built-in)")
+		}
+		errFmt.w.WriteString(" in ")
+		errFmt.location(errFmt.fileOf(f.Span), f.Span.Start)
+		errFmt.w.WriteRune('\n')
+	}
 }
 
 // writes rune 'r' 'n' times into 'builder'
@@ -0,0 +1,221 @@
+package serror
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core"
+	"github.com/xnacly/sophia/core/token"
+)
+
+const src = "(let a 1)\n(println b)"
+
+// token of b in src
+var tok = &token.Token{
+	Pos:     19,
+	Line:    1,
+	LinePos: 9,
+	End:     token.Position{Offset: 20, Line: 1, Column: 10},
+	Type:    token.IDENT,
+	Raw:     "b",
+}
+
+func display(format string) string {
+	b := &bytes.Buffer{}
+	f := NewFormatter(&core.Config{ErrorFormat: format}, src, "cli", b)
+	f.Add(tok, "Undefined variable", "Variable %q is not defined.", "b")
+	f.Display()
+	return b.String()
+}
+
+func TestDisplayText(t *testing.T) {
+	out := display(FORMAT_TEXT)
+	if strings.Contains(out, "\033[") {
+		t.Errorf("expected no ansi escape codes if not writing to a terminal, got %q", out)
+	}
+	for _, s := range []string{"error[S0024]: Undefined variable", "cli:2:10:", "    2| (println b)", "^", "Variable \"b\" is not defined."} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in %q", s, out)
+		}
+	}
+}
+
+func TestDisplayTooMany(t *testing.T) {
+	b := &bytes.Buffer{}
+	f := NewFormatter(&core.Config{}, src, "cli", b)
+	for i := 0; i <= MAX_ERRORS; i++ {
+		f.Add(tok, "Undefined variable", "Variable %q is not defined (%d).", "b", i)
+	}
+	f.Display()
+	if !strings.HasSuffix(b.String(), "to view all 4 errors\n") {
+		t.Errorf("expected the skipped errors to be reported in a line of its own, got %q", b.String())
+	}
+}
+
+func TestDisplayJSON(t *testing.T) {
+	out := display(FORMAT_JSON)
+	lines := strings.Split(strings.TrimSpace(out), "\n")
+	if len(lines) != 1 {
+		t.Fatalf("expected one object per error, got %q", out)
+	}
+	e := jsonError{}
+	if err := json.Unmarshal([]byte(lines[0]), &e); err != nil {
+		t.Fatal(err)
+	}
+	exp := jsonError{
+		File:   "cli",
+		Line:   2,
+		Column: 10,
+		Span: jsonSpan{
+			Start: jsonPosition{Offset: 19, Line: 2, Column: 10},
+			End:   jsonPosition{Offset: 20, Line: 2, Column: 11},
+		},
+		Title:    "Undefined variable",
+		Info:     "Variable \"b\" is not defined.",
+		Severity: "error",
+		Code:     "S0024",
+	}
+	if !reflect.DeepEqual(e, exp) {
+		t.Errorf("wanted %+v, got %+v", exp, e)
+	}
+}
+
+func TestDisplaySARIF(t *testing.T) {
+	log := sarifLog{}
+	if err := json.Unmarshal([]byte(display(FORMAT_SARIF)), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != "2.1.0" || len(log.Runs) != 1 || len(log.Runs[0].Results) != 1 {
+		t.Fatalf("unexpected sarif log %+v", log)
+	}
+	result := log.Runs[0].Results[0]
+	region := result.Locations[0].PhysicalLocation.Region
+	if result.Level != "error" || result.Message.Text != "Variable \"b\" is not defined." {
+		t.Errorf("unexpected result %+v", result)
+	}
+	if region.StartLine != 2 || region.StartColumn != 10 || region.EndLine != 2 || region.EndColumn != 11 {
+		t.Errorf("unexpected region %+v", region)
+	}
+}
+
+func TestSeverities(t *testing.T) {
+	f := NewFormatter(&core.Config{}, src, "cli", &bytes.Buffer{})
+	f.Add(tok, "Unused variable", "Variable %q is defined but never used.", "b")
+	f.Add(tok, "Shadowed variable", "%q shadows the variable defined in line %d.", "b", 1)
+	if f.HasErrors() {
+		t.Errorf("warnings must not be errors")
+	}
+	if f.errors[0].Severity != WARNING || f.errors[1].Severity != WARNING {
+		t.Errorf("unexpected severities %s and %s", f.errors[0].Severity, f.errors[1].Severity)
+	}
+	f.Add(tok, "Undefined variable", "Variable %q is not defined.", "b")
+	if !f.HasErrors() {
+		t.Errorf("expected errors")
+	}
+}
+
+func TestDuplicates(t *testing.T) {
+	f := NewFormatter(&core.Config{}, src, "cli", &bytes.Buffer{})
+	for i := 0; i < 3; i++ {
+		f.Add(tok, "Undefined variable", "Variable %q is not defined.", "b")
+	}
+	f.Add(tok, "Type error", "Expected value of type float, got string")
+	f.Add(tok, "Undefined variable", "Variable %q is not defined.", "b")
+	if len(f.errors) != 3 {
+		t.Errorf("expected consecutive duplicates to be added once, got %d errors", len(f.errors))
+	}
+}
+
+func TestLabelsAndHelp(t *testing.T) {
+	b := &bytes.Buffer{}
+	f := NewFormatter(&core.Config{}, src, "cli", b)
+	f.Add(tok, "Undefined variable", "Variable %q is not defined.", "b").
+		Label(token.Span{Start: token.Position{Column: 5}, End: token.Position{Column: 6}}, "similar variable").
+		Suggest("vlaue", []string{"a", "value", "println"})
+	f.Display()
+	for _, s := range []string{"    1| (let a 1)\n\t     |      - similar variable", "help: did you mean `value`?", "sophia explain S0024"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected %q in %q", s, b.String())
+		}
+	}
+}
+
+func TestCodes(t *testing.T) {
+	codes, titles := map[string]bool{}, map[string]bool{}
+	for _, c := range CODES {
+		if codes[c.Code] || titles[c.Title] {
+			t.Errorf("duplicate code %s or title %q", c.Code, c.Title)
+		}
+		codes[c.Code], titles[c.Title] = true, true
+		if c.Explanation == "" {
+			t.Errorf("missing explanation for %s", c.Code)
+		}
+	}
+	if c, ok := LookupCode("s0024"); !ok || c.Title != "Undefined variable" {
+		t.Errorf("failed to lookup code s0024, got %+v", c)
+	}
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"println", "print", "len", "float", "value"}
+	tests := []struct {
+		name string
+		exp  string
+	}{
+		{name: "printn", exp: "print"},
+		{name: "printlnn", exp: "println"},
+		{name: "flaot", exp: "float"},
+		{name: "lne", exp: "len"},
+		{name: "x", exp: ""},
+		{name: "something", exp: ""},
+	}
+	for _, test := range tests {
+		if got, _ := Closest(test.name, candidates); got != test.exp {
+			t.Errorf("wanted %q for %q, got %q", test.exp, test.name, got)
+		}
+	}
+}
+
+func TestLoadedFiles(t *testing.T) {
+	b := &bytes.Buffer{}
+	f := NewFormatter(&core.Config{}, "(load \"lib.phia\")", "cli", b)
+	from := &token.Token{Pos: 6, LinePos: 6, End: token.Position{Offset: 16, Column: 16}, Type: token.STRING, Raw: "lib.phia", File: "cli"}
+	f.AddSource("lib.phia", "(let a 1)\n(let b 2)\n(println c)", from)
+	f.Add(&token.Token{Pos: 29, Line: 2, LinePos: 9, End: token.Position{Offset: 30, Line: 2, Column: 10}, Type: token.IDENT, Raw: "c", File: "lib.phia"},
+		"Undefined variable", "Variable %q is not defined.", "c")
+	f.Display()
+	for _, s := range []string{"lib.phia:3:10:", "imported from: cli:1:7", "    3| (println c)"} {
+		if !strings.Contains(b.String(), s) {
+			t.Errorf("expected %q in %q", s, b.String())
+		}
+	}
+	if chain := f.ImportedFrom("lib.phia"); len(chain) != 1 || chain[0] != from {
+		t.Errorf("unexpected import chain %v", chain)
+	}
+	if chain := f.ImportedFrom("cli"); len(chain) != 0 {
+		t.Errorf("expected no import chain for the file executed, got %v", chain)
+	}
+}
+
+func TestTrace(t *testing.T) {
+	b := &bytes.Buffer{}
+	f := NewFormatter(&core.Config{}, src, "cli", b)
+	err := f.Add(tok, "Undefined variable", "Variable %q is not defined.", "b")
+	for i := 0; i < MAX_FRAMES+4; i++ {
+		err.Trace = append(err.Trace, Frame{Name: "f", Span: tok.Span()})
+	}
+	err.Trace = append(err.Trace, Frame{Name: TOP_LEVEL, Span: tok.Span()})
+	f.Display()
+	out := b.String()
+	for _, s := range []string{"stack trace (most recent call first):\n\tat f in cli:2:10\n", "... 5 frames omitted ...", "at <top level> in cli:2:10"} {
+		if !strings.Contains(out, s) {
+			t.Errorf("expected %q in %q", s, out)
+		}
+	}
+	if n := strings.Count(out, "\tat "); n != MAX_FRAMES {
+		t.Errorf("expected %d frames, got %d", MAX_FRAMES, n)
+	}
+}
@@ -0,0 +1,184 @@
+package serror
+
+import (
+	"encoding/json"
+	"io"
+	"path/filepath"
+
+	"github.com/xnacly/sophia/core/token"
+)
+
+// position of an error in the json output, lines and columns start at 1
+type jsonPosition struct {
+	Offset int `json:"offset"`
+	Line   int `json:"line"`
+	Column int `json:"column"`
+}
+
+type jsonSpan struct {
+	Start jsonPosition `json:"start"`
+	End   jsonPosition `json:"end"`
+}
+
+type jsonLabel struct {
+	Span    jsonSpan `json:"span"`
+	Message string   `json:"message"`
+}
+
+// load statement importing the file an error occurred in
+type jsonImport struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+}
+
+// frame of the stack trace of a runtime error
+type jsonFrame struct {
+	Name    string `json:"name"`
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Builtin bool   `json:"builtin,omitempty"`
+}
+
+type jsonError struct {
+	File     string      `json:"file"`
+	Line     int         `json:"line"`
+	Column   int         `json:"column"`
+	Span     jsonSpan    `json:"span"`
+	Title    string      `json:"title"`
+	Info     string      `json:"info"`
+	Severity string      `json:"severity"`
+	Code     string      `json:"code,omitempty"`
+	Labels   []jsonLabel `json:"labels,omitempty"`
+	Help     string      `json:"help,omitempty"`
+	// chain of load statements leading to the file, innermost first
+	ImportedFrom []jsonImport `json:"importedFrom,omitempty"`
+	// stack trace of runtime errors, innermost frame first
+	Trace []jsonFrame `json:"trace,omitempty"`
+}
+
+func newJSONSpan(s token.Span) jsonSpan {
+	return jsonSpan{
+		Start: jsonPosition{Offset: s.Start.Offset, Line: s.Start.Line + 1, Column: s.Start.Column + 1},
+		End:   jsonPosition{Offset: s.End.Offset, Line: s.End.Line + 1, Column: s.End.Column + 1},
+	}
+}
+
+// writes all errors as json objects, one per line
+func (e *ErrorFormatter) json() {
+	enc := json.NewEncoder(e.w)
+	for _, err := range e.errors {
+		j := jsonError{
+			File:     path(e.fileOf(err.Span)),
+			Line:     err.Span.Start.Line + 1,
+			Column:   err.Span.Start.Column + 1,
+			Span:     newJSONSpan(err.Span),
+			Title:    err.Title,
+			Info:     err.Info,
+			Severity: err.Severity.String(),
+			Code:     err.Code,
+			Help:     err.Help,
+		}
+		for _, l := range err.Labels {
+			j.Labels = append(j.Labels, jsonLabel{Span: newJSONSpan(l.Span), Message: l.Message})
+		}
+		for _, t := range e.ImportedFrom(e.fileOf(err.Span)) {
+			j.ImportedFrom = append(j.ImportedFrom, jsonImport{File: path(t.File), Line: t.Line + 1, Column: t.LinePos + 1})
+		}
+		for _, f := range err.Trace {
+			j.Trace = append(j.Trace, jsonFrame{
+				Name:    f.Name,
+				File:    path(e.fileOf(f.Span)),
+				Line:    f.Span.Start.Line + 1,
+				Column:  f.Span.Start.Column + 1,
+				Builtin: f.Builtin,
+			})
+		}
+		enc.Encode(j)
+	}
+	e.w.Flush()
+}
+
+// subset of the static analysis results interchange format, see
+// https://docs.oasis-open.org/sarif/sarif/v2.1.0/sarif-v2.1.0.html
+type sarifLog struct {
+	Version string     `json:"version"`
+	Schema  string     `json:"$schema"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Name           string `json:"name"`
+			InformationURI string `json:"informationUri"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifResult struct {
+	RuleID  string `json:"ruleId"`
+	Level   string `json:"level"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []sarifLocation `json:"locations"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation struct {
+		ArtifactLocation struct {
+			URI string `json:"uri"`
+		} `json:"artifactLocation"`
+		Region struct {
+			StartLine   int `json:"startLine"`
+			StartColumn int `json:"startColumn"`
+			EndLine     int `json:"endLine"`
+			EndColumn   int `json:"endColumn"`
+		} `json:"region"`
+	} `json:"physicalLocation"`
+}
+
+// writes all errors as a sarif log, used for annotating source code in
+// continuous integration
+func (e *ErrorFormatter) sarif() {
+	WriteSARIF(e.w, e)
+	e.w.Flush()
+}
+
+// writes the errors of all formatters as a single sarif log to w
+func WriteSARIF(w io.Writer, formatters ...*ErrorFormatter) error {
+	run := sarifRun{Results: make([]sarifResult, 0)}
+	run.Tool.Driver.Name = "sophia"
+	run.Tool.Driver.InformationURI = "https://github.com/xnacly/sophia"
+	for _, f := range formatters {
+		for _, err := range f.errors {
+			result := sarifResult{RuleID: err.Code, Level: err.Severity.String()}
+			if result.RuleID == "" {
+				result.RuleID = err.Title
+			}
+			result.Message.Text = err.Info
+			if err.Help != "" {
+				result.Message.Text += " (" + err.Help + ")"
+			}
+			l := sarifLocation{}
+			l.PhysicalLocation.ArtifactLocation.URI = filepath.ToSlash(f.fileOf(err.Span))
+			region := &l.PhysicalLocation.Region
+			region.StartLine = err.Span.Start.Line + 1
+			region.StartColumn = err.Span.Start.Column + 1
+			region.EndLine = max(err.Span.End.Line+1, region.StartLine)
+			region.EndColumn = max(err.Span.End.Column+1, region.StartColumn)
+			result.Locations = []sarifLocation{l}
+			run.Results = append(run.Results, result)
+		}
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(sarifLog{
+		Version: "2.1.0",
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Runs:    []sarifRun{run},
+	})
+}
@@ -0,0 +1,48 @@
+package serror
+
+import "unicode/utf8"
+
+// returns the candidate most similar to name by edit distance, candidates
+// differing in more than about a third of the characters of name are not
+// considered similar
+func Closest(name string, candidates []string) (string, bool) {
+	best, bestDistance := "", (utf8.RuneCountInString(name)+1)/3+1
+	for _, c := range candidates {
+		if c == name {
+			continue
+		}
+		d := distance(name, c)
+		if d < bestDistance || d == bestDistance && best != "" && c < best {
+			best, bestDistance = c, d
+		}
+	}
+	return best, best != ""
+}
+
+// edit distance between a and b counted in runes: the amount of insertions,
+// deletions, substitutions and transpositions of adjacent runes needed to
+// turn a into b
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	d := make([][]int, len(ra)+1)
+	for i := range d {
+		d[i] = make([]int, len(rb)+1)
+		d[i][0] = i
+	}
+	for j := range d[0] {
+		d[0][j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			d[i][j] = min(d[i-1][j]+1, d[i][j-1]+1, d[i-1][j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				d[i][j] = min(d[i][j], d[i-2][j-2]+1)
+			}
+		}
+	}
+	return d[len(ra)][len(rb)]
+}
@@ -1,14 +1,13 @@
 package shared
 
 import (
-	"fmt"
 	"github.com/xnacly/sophia/core/types"
 	"strconv"
 	"strings"
 )
 
 // formats the given children by executing them, skips fmt.Sprint for string,
-// float64 and booleans. Uses a passed in buffer for skipping memory
+// float64, decimals and booleans. Uses a passed in buffer for skipping memory
 // allocation for each call. Remember to reset the buffer before calling this
 // function.
 func FormatHelper(buffer *strings.Builder, children []types.Node, sep rune) {
@@ -16,20 +15,28 @@ func FormatHelper(buffer *strings.Builder, children []types.Node, sep rune) {
 		if i != 0 && sep != 0 {
 			buffer.WriteRune(sep)
 		}
-		v := c.Eval()
-		switch v := v.(type) {
-		case string:
-			buffer.WriteString(v)
-		case float64:
-			buffer.WriteString(strconv.FormatFloat(v, 'g', 12, 64))
-		case bool:
-			if v {
-				buffer.WriteString("true")
-			} else {
-				buffer.WriteString("false")
-			}
-		default:
-			fmt.Fprint(buffer, v)
+		FormatValue(buffer, c.Eval())
+	}
+}
+
+// writes the string representation of v into buffer, strings are written
+// without quotes
+func FormatValue(buffer *strings.Builder, v any) {
+	switch v := v.(type) {
+	case string:
+		buffer.WriteString(v)
+	case float64:
+		buffer.WriteString(strconv.FormatFloat(v, 'g', 12, 64))
+	case types.Decimal:
+		buffer.WriteString(v.String())
+	case bool:
+		if v {
+			buffer.WriteString("true")
+		} else {
+			buffer.WriteString("false")
 		}
+	default:
+		// nested values are written in sophia syntax: [1 "a" true]
+		WriteRepr(buffer, v)
 	}
 }
@@ -0,0 +1,109 @@
+package shared
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/xnacly/sophia/core/types"
+)
+
+// returns the representation of v in sophia syntax, such as [1 "a" true] or
+// {name: "anon"}, see WriteRepr
+func Repr(v any) string {
+	b := &strings.Builder{}
+	WriteRepr(b, v)
+	return b.String()
+}
+
+// writes the representation of v in sophia syntax into buffer. Strings are
+// quoted and escaped, decimals are suffixed with d, object keys are sorted.
+// Arrays and objects containing themselves are written as [...] and {...}.
+func WriteRepr(buffer *strings.Builder, v any) {
+	writeRepr(buffer, v, map[uintptr]bool{})
+}
+
+// seen contains the arrays and objects currently written, to detect cycles
+func writeRepr(buffer *strings.Builder, v any, seen map[uintptr]bool) {
+	switch v := v.(type) {
+	case nil:
+		buffer.WriteString("nil")
+	case string:
+		quote(buffer, v)
+	case types.Decimal:
+		buffer.WriteString(v.String())
+		buffer.WriteRune('d')
+	case []any:
+		ptr := reflect.ValueOf(v).Pointer()
+		if len(v) != 0 && seen[ptr] {
+			buffer.WriteString("[...]")
+			return
+		}
+		seen[ptr] = true
+		defer delete(seen, ptr)
+		buffer.WriteRune('[')
+		for i, e := range v {
+			if i != 0 {
+				buffer.WriteRune(' ')
+			}
+			writeRepr(buffer, e, seen)
+		}
+		buffer.WriteRune(']')
+	case map[string]any:
+		ptr := reflect.ValueOf(v).Pointer()
+		if seen[ptr] {
+			buffer.WriteString("{...}")
+			return
+		}
+		seen[ptr] = true
+		defer delete(seen, ptr)
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		buffer.WriteRune('{')
+		for i, k := range keys {
+			if i != 0 {
+				buffer.WriteRune(' ')
+			}
+			buffer.WriteString(k)
+			buffer.WriteString(": ")
+			writeRepr(buffer, v[k], seen)
+		}
+		buffer.WriteRune('}')
+	case float64, bool:
+		FormatValue(buffer, v)
+	default:
+		fmt.Fprint(buffer, v)
+	}
+}
+
+// writes s as a double quoted string, escaping quotes, backslashes and
+// control characters via the escape sequences known to the lexer
+func quote(buffer *strings.Builder, s string) {
+	buffer.WriteRune('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			buffer.WriteString(`\"`)
+		case '\\':
+			buffer.WriteString(`\\`)
+		case '\n':
+			buffer.WriteString(`\n`)
+		case '\t':
+			buffer.WriteString(`\t`)
+		case '\r':
+			buffer.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				buffer.WriteString(`\u{` + strconv.FormatInt(int64(r), 16) + `}`)
+			} else {
+				buffer.WriteRune(r)
+			}
+		}
+	}
+	buffer.WriteRune('"')
+}
@@ -0,0 +1,49 @@
+package shared
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/xnacly/sophia/core/types"
+)
+
+func TestRepr(t *testing.T) {
+	half, _ := types.NewDecimal("0.5")
+	cyclicArray := []any{1.0, nil}
+	cyclicArray[1] = cyclicArray
+	cyclicObject := map[string]any{"name": "anon"}
+	cyclicObject["self"] = cyclicObject
+	shared := []any{1.0}
+
+	tests := []struct {
+		v   any
+		exp string
+	}{
+		{v: nil, exp: "nil"},
+		{v: 12.5, exp: "12.5"},
+		{v: half, exp: "0.5d"},
+		{v: true, exp: "true"},
+		{v: "a \"quoted\"\n\\", exp: `"a \"quoted\"\n\\"`},
+		{v: []any{1.0, "a", true}, exp: `[1 "a" true]`},
+		{v: []any{}, exp: "[]"},
+		{v: map[string]any{"name": "anon", "age": 25.0, "tags": []any{"a"}}, exp: `{age: 25 name: "anon" tags: ["a"]}`},
+		{v: cyclicArray, exp: "[1 [...]]"},
+		{v: cyclicObject, exp: `{name: "anon" self: {...}}`},
+		{v: []any{shared, shared}, exp: "[[1] [1]]"},
+	}
+	for _, test := range tests {
+		if got := Repr(test.v); got != test.exp {
+			t.Errorf("wanted %s, got %s", test.exp, got)
+		}
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	b := &strings.Builder{}
+	FormatValue(b, "top level strings are not quoted")
+	b.WriteRune(' ')
+	FormatValue(b, []any{"nested", map[string]any{"b": 1.0, "a": "x"}})
+	if exp := `top level strings are not quoted ["nested" {a: "x" b: 1}]`; b.String() != exp {
+		t.Errorf("wanted %s, got %s", exp, b.String())
+	}
+}
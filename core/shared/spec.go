@@ -0,0 +1,151 @@
+package shared
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/xnacly/sophia/core/types"
+)
+
+// FormatSpec is the parsed representation of a template string format
+// specifier: [align][0][width][.precision][verb], for instance '>8.2f'.
+type FormatSpec struct {
+	Align     rune // '<', '>', '^' or 0 for the default alignment
+	Zero      bool // pad numbers with zeros instead of spaces
+	Width     int
+	Precision int  // -1 if not specified
+	Verb      rune // 'f', 'e', 'g', 'd', 's' or 0 for the default verb
+}
+
+// parses a format specifier, such as '.2f', '<10' or '08.3f'
+func ParseFormatSpec(spec string) (FormatSpec, error) {
+	f := FormatSpec{Precision: -1}
+	i := 0
+	if i < len(spec) && strings.ContainsRune("<>^", rune(spec[i])) {
+		f.Align = rune(spec[i])
+		i++
+	}
+	if i < len(spec) && spec[i] == '0' {
+		f.Zero = true
+		i++
+	}
+	start := i
+	for i < len(spec) && spec[i] >= '0' && spec[i] <= '9' {
+		i++
+	}
+	if i > start {
+		f.Width, _ = strconv.Atoi(spec[start:i])
+	}
+	if i < len(spec) && spec[i] == '.' {
+		i++
+		start = i
+		for i < len(spec) && spec[i] >= '0' && spec[i] <= '9' {
+			i++
+		}
+		if i == start {
+			return f, errors.New("expected digits after '.' for the precision")
+		}
+		f.Precision, _ = strconv.Atoi(spec[start:i])
+	}
+	if i < len(spec) {
+		if !strings.ContainsRune("fegds", rune(spec[i])) {
+			return f, fmt.Errorf("unknown verb %q, expected any of 'f', 'e', 'g', 'd' or 's'", spec[i])
+		}
+		f.Verb = rune(spec[i])
+		i++
+	}
+	if i < len(spec) {
+		return f, fmt.Errorf("unexpected %q after the format specifier", spec[i:])
+	}
+	return f, nil
+}
+
+// formats v according to the specifier, returns an error if the verb is not
+// applicable to the type of v
+func (f FormatSpec) Format(v any) (string, error) {
+	var s string
+	number := false
+	switch v := v.(type) {
+	case float64:
+		number = true
+		switch f.Verb {
+		case 0:
+			if f.Precision >= 0 {
+				s = strconv.FormatFloat(v, 'f', f.Precision, 64)
+			} else {
+				s = strconv.FormatFloat(v, 'g', 12, 64)
+			}
+		case 'd':
+			s = strconv.FormatFloat(math.Round(v), 'f', 0, 64)
+		case 's':
+			number = false
+			s = strconv.FormatFloat(v, 'g', 12, 64)
+		default:
+			s = strconv.FormatFloat(v, byte(f.Verb), f.Precision, 64)
+		}
+	case types.Decimal:
+		number = true
+		switch f.Verb {
+		case 0, 'f':
+			if f.Precision >= 0 {
+				s = v.FloatString(f.Precision)
+			} else {
+				s = v.String()
+			}
+		case 'd':
+			s = v.FloatString(0)
+		case 's':
+			number = false
+			s = v.String()
+		default:
+			fl, _ := v.Float64()
+			s = strconv.FormatFloat(fl, byte(f.Verb), f.Precision, 64)
+		}
+	default:
+		if f.Verb != 0 && f.Verb != 's' {
+			return "", fmt.Errorf("verb %q requires a number, got %T", f.Verb, v)
+		}
+		b := strings.Builder{}
+		FormatValue(&b, v)
+		s = b.String()
+		if f.Precision >= 0 && utf8.RuneCountInString(s) > f.Precision {
+			s = string([]rune(s)[:f.Precision])
+		}
+	}
+	return f.pad(s, number), nil
+}
+
+// pads s to the width of the specifier, numbers are aligned to the right and
+// all other values to the left by default
+func (f FormatSpec) pad(s string, number bool) string {
+	n := f.Width - utf8.RuneCountInString(s)
+	if n <= 0 {
+		return s
+	}
+	if f.Zero && number && f.Align == 0 {
+		sign := ""
+		if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+			sign, s = s[:1], s[1:]
+		}
+		return sign + strings.Repeat("0", n) + s
+	}
+	align := f.Align
+	if align == 0 {
+		align = '<'
+		if number {
+			align = '>'
+		}
+	}
+	switch align {
+	case '>':
+		return strings.Repeat(" ", n) + s
+	case '^':
+		return strings.Repeat(" ", n/2) + s + strings.Repeat(" ", n-n/2)
+	default:
+		return s + strings.Repeat(" ", n)
+	}
+}
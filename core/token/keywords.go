@@ -2,6 +2,7 @@ package token
 
 var CONSTANTS = []int{
 	FLOAT,
+	DECIMAL,
 	STRING,
 	IDENT,
 	BOOL,
@@ -1,9 +1,67 @@
 package token
 
+// Position in a source file, lines and columns are zero based, columns are
+// counted in runes
+type Position struct {
+	Offset int // byte offset from the start of the file
+	Line   int
+	Column int
+}
+
+// Span of source code, End is the position directly after the last character
+type Span struct {
+	File  string
+	Start Position
+	End   Position
+}
+
+// reports whether the span contains the given line and column
+func (s Span) Contains(line, column int) bool {
+	if line < s.Start.Line || line > s.End.Line {
+		return false
+	}
+	if line == s.Start.Line && column < s.Start.Column {
+		return false
+	}
+	if line == s.End.Line && column >= s.End.Column {
+		return false
+	}
+	return true
+}
+
+// returns a span covering both s and o
+func (s Span) Join(o Span) Span {
+	if o.Start.Offset < s.Start.Offset {
+		s.Start = o.Start
+	}
+	if o.End.Offset > s.End.Offset {
+		s.End = o.End
+	}
+	return s
+}
+
 type Token struct {
-	Pos     int
-	Line    int
-	LinePos int
+	Pos     int // byte offset of the start of the token
+	Line    int // zero based line of the start of the token
+	LinePos int // zero based column of the start of the token, counted in runes
+	End     Position
+	File    string
 	Type    int
 	Raw     string
 }
+
+// returns the span of source code the token was lexed from
+func (t *Token) Span() Span {
+	if t == nil {
+		return Span{}
+	}
+	return Span{
+		File: t.File,
+		Start: Position{
+			Offset: t.Pos,
+			Line:   t.Line,
+			Column: t.LinePos,
+		},
+		End: t.End,
+	}
+}
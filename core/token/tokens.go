@@ -4,8 +4,12 @@ const (
 	UNKNOWN = iota + 1
 	// constants
 	FLOAT
+	DECIMAL
 	STRING
 	TEMPLATE_STRING
+	FORMAT      // format specifier of a template string interpolation
+	DOC_COMMENT // ;;; comment documenting the following definition
+	COMMENT     // ;; and #| |# comments, only emitted if requested
 	IDENT
 	BOOL
 
@@ -53,8 +57,12 @@ const (
 var TOKEN_NAME_MAP = map[int]string{
 	UNKNOWN:         "UNKNOWN",
 	FLOAT:           "float",
+	DECIMAL:         "decimal",
 	STRING:          "string",
 	TEMPLATE_STRING: "TEMPLATE_STRING",
+	FORMAT:          "format specifier",
+	DOC_COMMENT:     "doc comment",
+	COMMENT:         "comment",
 	IDENT:           "ident",
 	BOOL:            "bool",
 	ADD:             "+",
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// maximum amount of fractional digits displayed for decimals not representable
+// as a finite decimal fraction, such as 1/3
+const DECIMAL_MAX_PRECISION = 20
+
+// Decimal is the arbitrary precision number type of sophia, it is backed by a
+// rational number and therefore performs exact arithmetic
+type Decimal struct {
+	*big.Rat
+}
+
+// parses s as a decimal, such as "12.5", "1_000.99" or "1e-3"
+func NewDecimal(s string) (Decimal, bool) {
+	r, ok := new(big.Rat).SetString(strings.ReplaceAll(s, "_", ""))
+	return Decimal{r}, ok
+}
+
+// converts f into a decimal using its shortest string representation, thus
+// 0.1 results in 1/10 instead of the binary approximation of 0.1. Reports
+// false for NaN and infinities, which have no decimal representation.
+func DecimalFromFloat(f float64) (Decimal, bool) {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return Decimal{}, false
+	}
+	r, ok := new(big.Rat).SetString(strconv.FormatFloat(f, 'g', -1, 64))
+	return Decimal{r}, ok
+}
+
+// formats the decimal exactly if it is representable as a finite decimal
+// fraction, otherwise it is rounded to DECIMAL_MAX_PRECISION digits
+func (d Decimal) String() string {
+	if d.Rat == nil {
+		return "0"
+	}
+	if d.IsInt() {
+		return d.Num().String()
+	}
+	// a fraction is finite in base 10 only if its denominator consists of the
+	// prime factors 2 and 5, the larger power is the amount of digits needed
+	den := new(big.Int).Set(d.Denom())
+	twos, fives := 0, 0
+	two, five, rem := big.NewInt(2), big.NewInt(5), new(big.Int)
+	for rem.Mod(den, two).Sign() == 0 {
+		den.Quo(den, two)
+		twos++
+	}
+	for rem.Mod(den, five).Sign() == 0 {
+		den.Quo(den, five)
+		fives++
+	}
+	if den.Cmp(big.NewInt(1)) == 0 {
+		return d.FloatString(max(twos, fives))
+	}
+	s := d.FloatString(DECIMAL_MAX_PRECISION)
+	s = strings.TrimRight(s, "0")
+	return strings.TrimSuffix(s, ".")
+}
@@ -6,6 +6,8 @@ import (
 
 type Node interface {
 	GetToken() *token.Token
+	// span of source code the node was parsed from
+	GetSpan() token.Span
 	GetChildren() []Node
 	SetChildren(c []Node)
 	Eval() any
@@ -0,0 +1,47 @@
+package types
+
+// Type is the static type of a value, used for type annotations, such as
+// (let a:float 1), and by the type checker
+type Type string
+
+const (
+	ANY     Type = "any"
+	FLOAT   Type = "float"
+	DECIMAL Type = "decimal"
+	STRING  Type = "string"
+	BOOL    Type = "bool"
+	ARRAY   Type = "array"
+	OBJECT  Type = "object"
+)
+
+// types usable in type annotations
+var KNOWN_TYPES = map[Type]bool{
+	ANY:     true,
+	FLOAT:   true,
+	DECIMAL: true,
+	STRING:  true,
+	BOOL:    true,
+	ARRAY:   true,
+	OBJECT:  true,
+}
+
+// returns the type of the evaluated value v, any for values without a type
+// such as the result of println
+func Of(v any) Type {
+	switch v.(type) {
+	case float64:
+		return FLOAT
+	case Decimal:
+		return DECIMAL
+	case string:
+		return STRING
+	case bool:
+		return BOOL
+	case []any:
+		return ARRAY
+	case map[string]any:
+		return OBJECT
+	default:
+		return ANY
+	}
+}
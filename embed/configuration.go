@@ -9,4 +9,8 @@ type Configuration struct {
 	Functions map[string]types.KnownFunctionInterface
 	// enable debug logs
 	Debug bool
+	// format errors are displayed in: "text", "json" or "sarif", defaults
+	// to text. Text is only colored if written to a terminal and NO_COLOR
+	// is not set.
+	ErrorFormat string
 }
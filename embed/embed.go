@@ -21,7 +21,12 @@ func Embed(config Configuration) {
 		panic("Embedding error: Linking the go standard library via modules is currently not implemented")
 	}
 
+	if !serror.KnownFormat(config.ErrorFormat) {
+		panic("Embedding error: Unknown error format " + config.ErrorFormat + ", expected text, json or sarif")
+	}
+
 	core.CONF.Debug = config.Debug
+	core.CONF.ErrorFormat = config.ErrorFormat
 
 	for name, function := range config.Functions {
 		consts.FUNC_TABLE[alloc.NewFunc(name)] = function
@@ -34,7 +39,7 @@ func Execute(file *os.File, w io.Writer) error {
 	buf := &bytes.Buffer{}
 	r := io.TeeReader(file, buf)
 	buf.ReadFrom(r)
-	serror.SetDefault(serror.NewFormatter(&core.CONF, buf.String(), file.Name(), nil))
+	serror.SetDefault(serror.NewFormatter(&core.CONF, buf.String(), file.Name(), w))
 	_, err := run.Run(buf, file.Name())
 	return err
 }